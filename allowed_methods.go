@@ -0,0 +1,63 @@
+package michi
+
+import (
+	"net/http"
+	"slices"
+)
+
+// standardMethods are the methods probed by AllowedMethods in addition to the registered methods
+var standardMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// AllowedMethods returns the methods which r routes to a handler for the host and the path of req,
+// such as [GET HEAD POST] for "GET /items" and "POST /items". It returns nil if no route matches the path.
+//
+// The methods are decided by http.ServeMux itself like Explain, so a route registered without a method
+// allows all the standard methods, and GET allows HEAD.
+func (r *Router) AllowedMethods(req *http.Request) []string {
+	methods := slices.Clone(standardMethods)
+	for _, m := range r.registeredMethods() {
+		if !slices.Contains(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+	var allowed []string
+	for _, m := range methods {
+		probe := req.Clone(req.Context())
+		probe.Method = m
+		if r.Explain(probe).Outcome == "matched" {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed
+}
+
+// registeredMethods returns the methods of the routes of r and its sub routers
+func (r *Router) registeredMethods() []string {
+	var methods []string
+	for _, rt := range *r.routes {
+		if rt.sub != nil {
+			methods = append(methods, rt.sub.registeredMethods()...)
+		} else if rt.method != "" {
+			methods = append(methods, rt.method)
+		}
+	}
+	slices.Sort(methods)
+	return slices.Compact(methods)
+}
+
+// RouteMethods returns the methods allowed for the path of the request by the Router serving it.
+// Unlike RoutePattern, it is available before calling the next handler, so middlewares such as CORS
+// can answer preflight requests.
+func RouteMethods(r *http.Request) []string {
+	if m, ok := r.Context().Value(routePatternKey{}).(*matchedRoute); ok && m.router != nil {
+		return m.router.AllowedMethods(r)
+	}
+	return nil
+}
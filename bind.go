@@ -0,0 +1,466 @@
+package michi
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bindSources are the struct tags of Bind in the order of binding
+var bindSources = []string{"path", "query", "header", "form"}
+
+// defaultMaxMemory is the max memory of multipart forms like http.Request.FormValue
+const defaultMaxMemory = 32 << 20
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// FieldError is an error of a struct field in Bind.
+type FieldError struct {
+	// Field is the name of the struct field
+	Field string `json:"field"`
+	// Source is the struct tag of the value, one of "path", "query", "header" and "form"
+	Source string `json:"source"`
+	// Name is the name of the value in the source
+	Name string `json:"name"`
+	// Value is the invalid value
+	Value string `json:"value,omitempty"`
+	// Rule is the failed validation rule, it is empty if the value cannot be converted
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Source, e.Name, e.Message)
+}
+
+// BindErrors are the errors of all struct fields in Bind.
+type BindErrors []*FieldError
+
+func (e BindErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "michi: " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns the FieldErrors for errors.Is and errors.As
+func (e BindErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Bind sets the fields of the struct pointed to by dst from the request by struct tags,
+// and validates them by the validate tag.
+//
+//	type params struct {
+//		ID     int       `path:"id"`
+//		Page   int       `query:"page" validate:"min=1"`
+//		Tags   []string  `query:"tag" validate:"max=5"`
+//		Tenant string    `header:"X-Tenant" validate:"required"`
+//		Name   string    `form:"name" validate:"required,max=64"`
+//		Since  time.Time `query:"since"`
+//	}
+//
+// The tags are path for http.Request.PathValue, query for the URL query, header for the request
+// header and form for the form body. The fields are converted to strings, bools, ints, uints,
+// floats, time.Duration, encoding.TextUnmarshaler such as time.Time, pointers and slices of them.
+// Embedded structs are bound recursively, untagged fields are left unchanged.
+//
+// The validate tag is a comma separated list of rules:
+//
+//   - required: the value must be present and not empty
+//   - min=n, max=n: the number must be in the range, or the length of the string or slice
+//   - oneof=a b c: the value must be one of the space separated values
+//   - regexp=re: the string must match the regular expression, it must be the last rule
+//
+// All conversion and validation errors are returned as BindErrors, which WriteBindError writes as a 400 response.
+// The struct is checked once per type before binding, an unsupported field type, an unknown rule
+// or an invalid rule argument is returned as an error regardless of the request.
+func Bind(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("michi: Bind requires a non-nil pointer to a struct, got %T", dst)
+	}
+	if err := checkStruct(v.Elem().Type()); err != nil {
+		return err
+	}
+	b := &binder{req: r}
+	b.bindStruct(v.Elem())
+	if len(b.errs) > 0 {
+		return b.errs
+	}
+	return nil
+}
+
+// WriteBindError writes err returned by Bind.
+// BindErrors are written as a 400 Bad Request JSON response with the errors of all fields,
+// other errors are written as 500 Internal Server Error.
+func WriteBindError(w http.ResponseWriter, err error) {
+	var errs BindErrors
+	if !errors.As(err, &errs) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(struct {
+		Status int        `json:"status"`
+		Title  string     `json:"title"`
+		Errors BindErrors `json:"errors"`
+	}{
+		Status: http.StatusBadRequest,
+		Title:  http.StatusText(http.StatusBadRequest),
+		Errors: errs,
+	})
+}
+
+type binder struct {
+	req        *http.Request
+	formParsed bool
+	errs       BindErrors
+}
+
+// structChecks caches the results of checkStruct by the struct type
+var structChecks sync.Map
+
+// checkStruct returns an error if the struct type has a field which Bind cannot set or validate
+func checkStruct(t reflect.Type) error {
+	if err, ok := structChecks.Load(t); ok {
+		err, _ := err.(error)
+		return err
+	}
+	err := checkFields(t)
+	structChecks.Store(t, err)
+	return err
+}
+
+func checkFields(t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := checkFields(field.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, source := range bindSources {
+			if _, ok := field.Tag.Lookup(source); !ok {
+				continue
+			}
+			if !field.IsExported() {
+				return fmt.Errorf("michi: Bind cannot set unexported field %s", field.Name)
+			}
+			if !supported(field.Type, true) {
+				return fmt.Errorf("michi: Bind does not support %s of field %s", field.Type, field.Name)
+			}
+			if err := checkRules(field.Tag.Get("validate")); err != nil {
+				return fmt.Errorf("michi: field %s: %w", field.Name, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// supported reports whether setValue can convert a string to t, slices are supported if slice is true
+func supported(t reflect.Type, slice bool) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) || t == durationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Pointer:
+		return supported(t.Elem(), false)
+	case reflect.Slice:
+		return slice && supported(t.Elem(), false)
+	}
+	return false
+}
+
+// checkRules returns an error if the validate tag has an unknown rule or an invalid argument
+func checkRules(rules string) error {
+	for rules != "" {
+		var key, arg string
+		key, arg, rules = nextRule(rules)
+		switch key {
+		case "required", "oneof":
+		case "min", "max":
+			if _, err := strconv.ParseFloat(arg, 64); err != nil {
+				return fmt.Errorf("invalid %s rule %q", key, arg)
+			}
+		case "regexp":
+			if _, err := compileRegexp(arg); err != nil {
+				return fmt.Errorf("invalid regexp rule %q: %w", arg, err)
+			}
+		default:
+			return fmt.Errorf("unknown validation rule %q", key)
+		}
+	}
+	return nil
+}
+
+// nextRule returns the key and the argument of the first rule of the validate tag, and the remaining rules
+func nextRule(rules string) (string, string, string) {
+	var rule string
+	if strings.HasPrefix(rules, "regexp=") {
+		// the regular expression may contain commas
+		rule, rules = rules, ""
+	} else {
+		rule, rules, _ = strings.Cut(rules, ",")
+	}
+	key, arg, _ := strings.Cut(rule, "=")
+	return key, arg, rules
+}
+
+// bindStruct binds the fields of v, whose type is checked by checkStruct
+func (b *binder) bindStruct(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			b.bindStruct(v.Field(i))
+			continue
+		}
+		for _, source := range bindSources {
+			name, ok := field.Tag.Lookup(source)
+			if !ok {
+				continue
+			}
+			b.bindField(v.Field(i), field, source, name, b.values(source, name))
+			break
+		}
+	}
+}
+
+// values returns the values of the name in the source
+func (b *binder) values(source, name string) []string {
+	switch source {
+	case "path":
+		if value := b.req.PathValue(name); value != "" {
+			return []string{value}
+		}
+		return nil
+	case "query":
+		return b.req.URL.Query()[name]
+	case "header":
+		return b.req.Header.Values(name)
+	default:
+		if !b.formParsed {
+			b.formParsed = true
+			if err := b.parseForm(); err != nil {
+				b.errs = append(b.errs, &FieldError{Source: source, Name: name, Message: err.Error()})
+			}
+		}
+		return b.req.PostForm[name]
+	}
+}
+
+func (b *binder) parseForm() error {
+	mediaType, _, _ := mime.ParseMediaType(b.req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return b.req.ParseMultipartForm(defaultMaxMemory)
+	}
+	return b.req.ParseForm()
+}
+
+func (b *binder) bindField(v reflect.Value, field reflect.StructField, source, name string, values []string) {
+	fieldErr := func(value, rule, format string, args ...any) {
+		b.errs = append(b.errs, &FieldError{
+			Field:   field.Name,
+			Source:  source,
+			Name:    name,
+			Value:   value,
+			Rule:    rule,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	if len(values) > 0 {
+		// a slice implementing encoding.TextUnmarshaler such as net.IP is a single value
+		if v.Kind() == reflect.Slice && !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+			s := reflect.MakeSlice(v.Type(), len(values), len(values))
+			for i, value := range values {
+				if err := setValue(s.Index(i), value); err != nil {
+					fieldErr(value, "", "%v", err)
+					return
+				}
+			}
+			v.Set(s)
+		} else if err := setValue(v, values[0]); err != nil {
+			fieldErr(values[0], "", "%v", err)
+			return
+		}
+	}
+
+	rules := field.Tag.Get("validate")
+	for rules != "" {
+		var key, arg string
+		key, arg, rules = nextRule(rules)
+		value := strings.Join(values, ",")
+		if key != "required" && len(values) == 0 {
+			// optional values are validated only if present
+			continue
+		}
+		if msg := validate(v, values, key, arg); msg != "" {
+			fieldErr(value, key, "%s", msg)
+			return
+		}
+	}
+}
+
+// validate returns the error message if v does not satisfy the rule, which is checked by checkRules
+func validate(v reflect.Value, values []string, rule, arg string) string {
+	switch rule {
+	case "required":
+		if len(values) == 0 || values[0] == "" {
+			return "is required"
+		}
+	case "min", "max":
+		limit, _ := strconv.ParseFloat(arg, 64)
+		n, unit := measure(v)
+		if rule == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s%s", arg, unit)
+		}
+		if rule == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s%s", arg, unit)
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		for _, value := range values {
+			if !contains(options, value) {
+				return fmt.Sprintf("must be one of %s", strings.Join(options, ", "))
+			}
+		}
+	case "regexp":
+		re, _ := compileRegexp(arg)
+		for _, value := range values {
+			if !re.MatchString(value) {
+				return fmt.Sprintf("must match %s", arg)
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns the number for min and max rules, and the unit for the message
+func measure(v reflect.Value) (float64, string) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0, ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	case reflect.String:
+		return float64(len([]rune(v.String()))), " characters"
+	case reflect.Slice:
+		return float64(v.Len()), " values"
+	}
+	return 0, ""
+}
+
+// regexps caches the compiled regexp rules
+var regexps sync.Map
+
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(expr, re)
+	return re, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// setValue converts s to the type of v and sets it, the type is checked by supported
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid value %q", s)
+		}
+		return nil
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
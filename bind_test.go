@@ -0,0 +1,225 @@
+package michi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+)
+
+type pagination struct {
+	Page int `query:"page" validate:"min=1"`
+}
+
+type bindParams struct {
+	pagination
+	ID      int64         `path:"id"`
+	Tags    []string      `query:"tag" validate:"max=2"`
+	Active  *bool         `query:"active"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	IP      net.IP        `query:"ip"`
+	Proxies []net.IP      `query:"proxy"`
+	Tenant  string        `header:"X-Tenant" validate:"required,oneof=a b"`
+	Name    string        `form:"name" validate:"required,max=5,regexp=^[a-z,]+$"`
+	Ignored string
+}
+
+func TestBind(t *testing.T) {
+	active := true
+	tests := []struct {
+		name    string
+		url     string
+		header  map[string]string
+		body    string
+		want    bindParams
+		wantErr []michi.FieldError
+	}{
+		{
+			name:   "all values",
+			url:    "https://example.com/items/12?page=2&tag=x&tag=y&active=true&since=2024-01-02T03:04:05Z&timeout=1s&ip=192.0.2.1&proxy=192.0.2.2&proxy=192.0.2.3",
+			header: map[string]string{"X-Tenant": "a"},
+			body:   "name=a,b",
+			want: bindParams{
+				pagination: pagination{Page: 2},
+				ID:         12,
+				Tags:       []string{"x", "y"},
+				Active:     &active,
+				Since:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Timeout:    time.Second,
+				IP:         net.ParseIP("192.0.2.1"),
+				Proxies:    []net.IP{net.ParseIP("192.0.2.2"), net.ParseIP("192.0.2.3")},
+				Tenant:     "a",
+				Name:       "a,b",
+			},
+		},
+		{
+			name:   "optional values are not validated if absent",
+			url:    "https://example.com/items/12",
+			header: map[string]string{"X-Tenant": "b"},
+			body:   "name=c",
+			want: bindParams{
+				ID:     12,
+				Tenant: "b",
+				Name:   "c",
+			},
+		},
+		{
+			name:   "all errors",
+			url:    "https://example.com/items/x?page=0&tag=1&tag=2&tag=3&active=yes",
+			header: map[string]string{"X-Tenant": "c"},
+			body:   "",
+			wantErr: []michi.FieldError{
+				{Field: "Page", Source: "query", Name: "page", Value: "0", Rule: "min", Message: "must be at least 1"},
+				{Field: "ID", Source: "path", Name: "id", Value: "x", Message: `invalid integer "x"`},
+				{Field: "Tags", Source: "query", Name: "tag", Value: "1,2,3", Rule: "max", Message: "must be at most 2 values"},
+				{Field: "Active", Source: "query", Name: "active", Value: "yes", Message: `invalid boolean "yes"`},
+				{Field: "Tenant", Source: "header", Name: "X-Tenant", Value: "c", Rule: "oneof", Message: "must be one of a, b"},
+				{Field: "Name", Source: "form", Name: "name", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:   "regexp",
+			url:    "https://example.com/items/1",
+			header: map[string]string{"X-Tenant": "a"},
+			body:   "name=A",
+			wantErr: []michi.FieldError{
+				{Field: "Name", Source: "form", Name: "name", Value: "A", Rule: "regexp", Message: "must match ^[a-z,]+$"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindParams
+			var err error
+			r := michi.NewRouter()
+			r.HandleFunc("POST /items/{id}", func(w http.ResponseWriter, req *http.Request) {
+				err = michi.Bind(req, &got)
+			})
+			req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			var gotErr []michi.FieldError
+			var errs michi.BindErrors
+			if errors.As(err, &errs) {
+				for _, e := range errs {
+					gotErr = append(gotErr, *e)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotErr, tt.wantErr) {
+				t.Errorf("Bind error got: %+v want: %+v", gotErr, tt.wantErr)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind got: %+v want: %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindInvalidDestination(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	var s struct{}
+	if err := michi.Bind(req, s); err == nil {
+		t.Error("Bind must fail with a non pointer")
+	}
+}
+
+func TestBindInvalidStruct(t *testing.T) {
+	tests := []struct {
+		name    string
+		dst     any
+		wantErr string
+	}{
+		{
+			name: "unsupported type",
+			dst: &struct {
+				Filter map[string]string `query:"filter"`
+			}{},
+			wantErr: "michi: Bind does not support map[string]string of field Filter",
+		},
+		{
+			name: "unsupported slice of slices",
+			dst: &struct {
+				Matrix [][]int `query:"m"`
+			}{},
+			wantErr: "michi: Bind does not support [][]int of field Matrix",
+		},
+		{
+			name: "unknown rule",
+			dst: &struct {
+				Name string `query:"name" validate:"required,len=3"`
+			}{},
+			wantErr: `michi: field Name: unknown validation rule "len"`,
+		},
+		{
+			name: "invalid min",
+			dst: &struct {
+				Page int `query:"page" validate:"min=one"`
+			}{},
+			wantErr: `michi: field Page: invalid min rule "one"`,
+		},
+		{
+			name: "invalid regexp",
+			dst: &struct {
+				Code string `query:"code" validate:"regexp=[a-z"`
+			}{},
+			wantErr: `michi: field Code: invalid regexp rule "[a-z"`,
+		},
+		{
+			name: "unexported field",
+			dst: &struct {
+				page int `query:"page"`
+			}{},
+			wantErr: "michi: Bind cannot set unexported field page",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the fields are absent in the request, the struct is invalid anyway
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			err := michi.Bind(req, tt.dst)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("Bind got: %v want: %v", err, tt.wantErr)
+			}
+			var errs michi.BindErrors
+			if errors.As(err, &errs) {
+				t.Errorf("Bind got: BindErrors want: an error of the struct")
+			}
+		})
+	}
+}
+
+func TestWriteBindError(t *testing.T) {
+	var params struct {
+		Page int `query:"page"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/?page=a", nil)
+	w := httptest.NewRecorder()
+	michi.WriteBindError(w, michi.Bind(req, &params))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("StatusCode got: %v want: %v", w.Code, http.StatusBadRequest)
+	}
+	var body struct {
+		Status int                `json:"status"`
+		Errors []michi.FieldError `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != http.StatusBadRequest || len(body.Errors) != 1 || body.Errors[0].Name != "page" {
+		t.Errorf("Body got: %+v", body)
+	}
+}
@@ -0,0 +1,362 @@
+// Package chicompat provides the chi API on top of michi.Router for a gradual migration from chi.
+//
+// Existing code compiles by replacing the chi import with chicompat, while routing is performed by
+// http.ServeMux. The patterns are converted to http.ServeMux patterns so that they match like chi:
+//
+//   - patterns match exactly, "/a/" is registered as "/a/{$}"
+//   - the catch-all "/*" is registered as "/{_...}", chicompat.URLParam(r, "*") returns its value
+//   - regexp parameters such as "{id:[0-9]+}" are registered as "{id}" and validated before the handler,
+//     the request is not found if the value does not match. They are not validated in Route and Mount patterns
+//   - Mount strips the mount path before routing in a mounted *Mux, handlers see the original r.URL
+//     and the path values of the mount pattern like chi
+//
+// The differences from chi are:
+//
+//   - http.ServeMux matches HEAD requests with GET patterns
+//   - http.ServeMux redirects "/a" to "/a/" for Route("/a") and Mount("/a") of a *Mux
+//     when there is no "/a" route, chi routes "/a" to the "/" route of the sub router
+//   - the most specific pattern wins regardless of the registration order
+//   - NotFound and MethodNotAllowed apply to the whole router tree created by Route, With and Group,
+//     not only to the sub router. A mounted *Mux uses those of the mounting Mux unless it has its own
+package chicompat
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-michi/michi"
+)
+
+// catchAll is the wildcard name of the chi catch-all "*"
+const catchAll = "_"
+
+// Router is the chi.Router interface implemented by Mux.
+type Router interface {
+	http.Handler
+
+	Use(middlewares ...func(http.Handler) http.Handler)
+	With(middlewares ...func(http.Handler) http.Handler) Router
+	Group(fn func(r Router)) Router
+	Route(pattern string, fn func(r Router)) Router
+	Mount(pattern string, h http.Handler)
+
+	Handle(pattern string, h http.Handler)
+	HandleFunc(pattern string, h http.HandlerFunc)
+	Method(method, pattern string, h http.Handler)
+	MethodFunc(method, pattern string, h http.HandlerFunc)
+
+	Connect(pattern string, h http.HandlerFunc)
+	Delete(pattern string, h http.HandlerFunc)
+	Get(pattern string, h http.HandlerFunc)
+	Head(pattern string, h http.HandlerFunc)
+	Options(pattern string, h http.HandlerFunc)
+	Patch(pattern string, h http.HandlerFunc)
+	Post(pattern string, h http.HandlerFunc)
+	Put(pattern string, h http.HandlerFunc)
+	Trace(pattern string, h http.HandlerFunc)
+
+	NotFound(h http.HandlerFunc)
+	MethodNotAllowed(h http.HandlerFunc)
+}
+
+// Mux is a chi compatible router using michi.Router.
+type Mux struct {
+	router *michi.Router
+	// handlers are shared with the routers created by With, Group and Route
+	handlers *handlers
+}
+
+type handlers struct {
+	notFound         http.HandlerFunc
+	methodNotAllowed http.HandlerFunc
+	// parent is the handlers of the Mux mounting this Mux, used if notFound or methodNotAllowed is nil
+	parent *handlers
+}
+
+// serveNotFound responds with the NotFound handler of the Mux or the Mux mounting it
+func (h *handlers) serveNotFound(w http.ResponseWriter, r *http.Request) {
+	for ; h != nil; h = h.parent {
+		if h.notFound != nil {
+			h.notFound(w, r)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// serveMethodNotAllowed responds with the MethodNotAllowed handler of the Mux or the Mux mounting it
+func (h *handlers) serveMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	for ; h != nil; h = h.parent {
+		if h.methodNotAllowed != nil {
+			h.methodNotAllowed(w, r)
+			return
+		}
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// NewRouter creates a new Mux
+func NewRouter() *Mux {
+	return &Mux{router: michi.NewRouter(), handlers: &handlers{}}
+}
+
+// NewMux creates a new Mux
+func NewMux() *Mux {
+	return NewRouter()
+}
+
+// ServeHTTP is the single method of the http.Handler interface.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value(stateKey{}).(*state); ok {
+		// mounted on another Mux
+		m.router.ServeHTTP(w, r)
+		return
+	}
+	m.router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), stateKey{}, &state{})))
+}
+
+// Use appends middlewares to the middleware stack.
+func (m *Mux) Use(middlewares ...func(http.Handler) http.Handler) {
+	m.router.Use(middlewares...)
+}
+
+// With adds inline middlewares for an endpoint handler.
+func (m *Mux) With(middlewares ...func(http.Handler) http.Handler) Router {
+	return &Mux{router: m.router.With(middlewares...), handlers: m.handlers}
+}
+
+// Group creates a new inline router with a copy of the middleware stack.
+func (m *Mux) Group(fn func(r Router)) Router {
+	r := m.With()
+	if fn != nil {
+		fn(r)
+	}
+	return r
+}
+
+// Route creates a new sub router and mounts it along the pattern.
+func (m *Mux) Route(pattern string, fn func(r Router)) Router {
+	pattern = convertPattern(strings.TrimSuffix(pattern, "/")).pattern
+	if pattern == "" {
+		pattern = "/"
+	}
+	var sub Router
+	m.router.Route(pattern, func(r *michi.Router) {
+		sub = &Mux{router: r, handlers: m.handlers}
+		fn(sub)
+	})
+	return sub
+}
+
+// Mount attaches a handler along the pattern.
+//
+// If h is a *Mux, the mount path is stripped before routing in h like chi.
+// Otherwise h receives the request as is.
+func (m *Mux) Mount(pattern string, h http.Handler) {
+	pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "*"), "/")
+	p := convertPattern(pattern)
+	if sub, ok := h.(*Mux); ok {
+		sub.handlers.parent = m.handlers
+		sub.router.NotFound(http.HandlerFunc(sub.handlers.serveNotFound))
+		sub.router.MethodNotAllowed(http.HandlerFunc(sub.handlers.serveMethodNotAllowed))
+		h = stripPrefix(len(strings.Split(p.pattern, "/"))-1, p.wildcards(), h)
+	}
+	if pattern != "" {
+		m.router.Handle(p.pattern, h)
+	}
+	m.router.Handle(p.pattern+"/", h)
+}
+
+// Handle adds the route pattern that matches any http method.
+func (m *Mux) Handle(pattern string, h http.Handler) {
+	p := convertPattern(pattern)
+	m.router.Handle(p.pattern, p.handler(h, m.handlers))
+}
+
+// HandleFunc adds the route pattern that matches any http method.
+func (m *Mux) HandleFunc(pattern string, h http.HandlerFunc) {
+	m.Handle(pattern, h)
+}
+
+// Method adds the route pattern that matches the http method.
+func (m *Mux) Method(method, pattern string, h http.Handler) {
+	p := convertPattern(pattern)
+	m.router.Handle(strings.ToUpper(method)+" "+p.pattern, p.handler(h, m.handlers))
+}
+
+// MethodFunc adds the route pattern that matches the http method.
+func (m *Mux) MethodFunc(method, pattern string, h http.HandlerFunc) {
+	m.Method(method, pattern, h)
+}
+
+// Connect adds the route pattern that matches a CONNECT http method.
+func (m *Mux) Connect(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodConnect, pattern, h)
+}
+
+// Delete adds the route pattern that matches a DELETE http method.
+func (m *Mux) Delete(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodDelete, pattern, h)
+}
+
+// Get adds the route pattern that matches a GET http method.
+// http.ServeMux also matches HEAD requests with it.
+func (m *Mux) Get(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodGet, pattern, h)
+}
+
+// Head adds the route pattern that matches a HEAD http method.
+func (m *Mux) Head(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodHead, pattern, h)
+}
+
+// Options adds the route pattern that matches an OPTIONS http method.
+func (m *Mux) Options(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodOptions, pattern, h)
+}
+
+// Patch adds the route pattern that matches a PATCH http method.
+func (m *Mux) Patch(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodPatch, pattern, h)
+}
+
+// Post adds the route pattern that matches a POST http method.
+func (m *Mux) Post(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodPost, pattern, h)
+}
+
+// Put adds the route pattern that matches a PUT http method.
+func (m *Mux) Put(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodPut, pattern, h)
+}
+
+// Trace adds the route pattern that matches a TRACE http method.
+func (m *Mux) Trace(pattern string, h http.HandlerFunc) {
+	m.Method(http.MethodTrace, pattern, h)
+}
+
+// NotFound sets the handler for requests not matching any route, by michi.Router.NotFound.
+// It is also used when a regexp parameter does not match.
+func (m *Mux) NotFound(h http.HandlerFunc) {
+	m.handlers.notFound = h
+	m.router.NotFound(http.HandlerFunc(m.handlers.serveNotFound))
+}
+
+// MethodNotAllowed sets the handler for requests matching a route with another method, by michi.Router.MethodNotAllowed.
+func (m *Mux) MethodNotAllowed(h http.HandlerFunc) {
+	m.handlers.methodNotAllowed = h
+	m.router.MethodNotAllowed(http.HandlerFunc(m.handlers.serveMethodNotAllowed))
+}
+
+// URLParam returns the path value of the key, "*" is the value of the catch-all.
+func URLParam(r *http.Request, key string) string {
+	if key == "*" {
+		key = catchAll
+	}
+	return r.PathValue(key)
+}
+
+// pattern is a chi pattern converted to the http.ServeMux pattern
+type pattern struct {
+	pattern string
+	// regexps are the regexps of the path values
+	regexps map[string]*regexp.Regexp
+}
+
+func convertPattern(chiPattern string) pattern {
+	p := pattern{regexps: map[string]*regexp.Regexp{}}
+	segs := strings.Split(chiPattern, "/")
+	for i, seg := range segs {
+		switch {
+		case seg == "*" && i == len(segs)-1:
+			segs[i] = "{" + catchAll + "...}"
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && strings.Contains(seg, ":"):
+			name, re, _ := strings.Cut(seg[1:len(seg)-1], ":")
+			segs[i] = "{" + name + "}"
+			p.regexps[name] = regexp.MustCompile("^(?:" + re + ")$")
+		}
+	}
+	p.pattern = strings.Join(segs, "/")
+	if strings.HasSuffix(p.pattern, "/") {
+		p.pattern += "{$}"
+	}
+	return p
+}
+
+// wildcards returns the wildcard names of the pattern
+func (p pattern) wildcards() []string {
+	var names []string
+	for _, seg := range strings.Split(p.pattern, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && seg != "{$}" {
+			names = append(names, strings.TrimSuffix(seg[1:len(seg)-1], "..."))
+		}
+	}
+	return names
+}
+
+// handler returns the handler validating the path values and restoring the request stripped by Mount.
+// It responds with the NotFound handler of handlers if a path value does not match the regexp.
+func (p pattern) handler(h http.Handler, handlers *handlers) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, re := range p.regexps {
+			if !re.MatchString(r.PathValue(name)) {
+				handlers.serveNotFound(w, r)
+				return
+			}
+		}
+		s, ok := r.Context().Value(stateKey{}).(*state)
+		if !ok || s.url == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		r = r.Clone(r.Context())
+		r.URL = s.url
+		for name, value := range s.values {
+			if r.PathValue(name) == "" {
+				r.SetPathValue(name, value)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// stripPrefix strips n segments of the path, and records the original URL and the path values of the mount pattern
+func stripPrefix(n int, wildcards []string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s, ok := r.Context().Value(stateKey{}).(*state); ok {
+			if s.url == nil {
+				s.url = r.URL
+			}
+			for _, name := range wildcards {
+				if s.values == nil {
+					s.values = map[string]string{}
+				}
+				s.values[name] = r.PathValue(name)
+			}
+		}
+		segs := strings.SplitN(r.URL.Path, "/", n+2)
+		path := "/"
+		if len(segs) == n+2 {
+			path += segs[n+1]
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL = &url.URL{}
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
+
+type stateKey struct{}
+
+// state is the routing state of a request shared by the mounted routers
+type state struct {
+	// url is the original URL before Mount strips the path
+	url *url.URL
+	// values are the path values of the mount patterns
+	values map[string]string
+}
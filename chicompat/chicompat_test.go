@@ -0,0 +1,254 @@
+package chicompat_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-michi/michi/chicompat"
+)
+
+func TestMux(t *testing.T) {
+	h := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.URL.Path + " " + chicompat.URLParam(r, "id") + chicompat.URLParam(r, "*")))
+		}
+	}
+	type want struct {
+		body       string
+		statusCode int
+	}
+	tests := []struct {
+		name   string
+		router func() http.Handler
+		method string
+		url    string
+		want   want
+	}{
+		{
+			name: "trailing slash matches exactly like chi",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Get("/a/", h("a"))
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/a/b",
+			want:   want{body: "404 page not found\n", statusCode: http.StatusNotFound},
+		},
+		{
+			name: "root matches exactly like chi",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Get("/", h("root"))
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/a",
+			want:   want{body: "404 page not found\n", statusCode: http.StatusNotFound},
+		},
+		{
+			name: "URLParam",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Get("/users/{id}", h("user"))
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/users/1",
+			want:   want{body: "user /users/1 1", statusCode: http.StatusOK},
+		},
+		{
+			name: "catch-all",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Get("/files/*", h("files"))
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/files/a/b",
+			want:   want{body: "files /files/a/b a/b", statusCode: http.StatusOK},
+		},
+		{
+			name: "regexp parameter matches",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Get("/users/{id:[0-9]+}", h("user"))
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/users/12",
+			want:   want{body: "user /users/12 12", statusCode: http.StatusOK},
+		},
+		{
+			name: "regexp parameter does not match",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Get("/users/{id:[0-9]+}", h("user"))
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/users/a",
+			want:   want{body: "404 page not found\n", statusCode: http.StatusNotFound},
+		},
+		{
+			name: "Route",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Route("/users", func(r chicompat.Router) {
+					r.Get("/", h("users"))
+					r.Post("/{id}", h("user"))
+				})
+				return r
+			},
+			method: http.MethodPost,
+			url:    "https://example.com/users/1",
+			want:   want{body: "user /users/1 1", statusCode: http.StatusOK},
+		},
+		{
+			name: "Route / matches only the trailing slash",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Route("/users", func(r chicompat.Router) {
+					r.Get("/", h("users"))
+				})
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/users/1",
+			want:   want{body: "404 page not found\n", statusCode: http.StatusNotFound},
+		},
+		{
+			name: "Mount strips the prefix for routing and keeps the original URL and path values",
+			router: func() http.Handler {
+				sub := chicompat.NewRouter()
+				sub.Get("/posts/{post}", func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(r.URL.Path + " " + chicompat.URLParam(r, "id") + " " + chicompat.URLParam(r, "post")))
+				})
+				r := chicompat.NewRouter()
+				r.Mount("/users/{id}", sub)
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/users/1/posts/2",
+			want:   want{body: "/users/1/posts/2 1 2", statusCode: http.StatusOK},
+		},
+		{
+			name: "Mount of http.Handler does not strip the prefix",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Mount("/static", h("static"))
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/static/a.css",
+			want:   want{body: "static /static/a.css ", statusCode: http.StatusOK},
+		},
+		{
+			name: "NotFound",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte("custom not found"))
+				})
+				r.Route("/users", func(r chicompat.Router) {
+					r.Get("/{id:[0-9]+}", h("user"))
+				})
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/users/a",
+			want:   want{body: "custom not found", statusCode: http.StatusNotFound},
+		},
+		{
+			name: "mounted Mux uses NotFound of the mounting Mux",
+			router: func() http.Handler {
+				sub := chicompat.NewRouter()
+				sub.Get("/{id:[0-9]+}", h("user"))
+				r := chicompat.NewRouter()
+				r.Mount("/users", sub)
+				r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte("custom not found"))
+				})
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/users/a/b",
+			want:   want{body: "custom not found", statusCode: http.StatusNotFound},
+		},
+		{
+			name: "NotFound is not used for 404 written by handlers",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("custom not found"))
+				})
+				r.Get("/a", http.NotFound)
+				return r
+			},
+			method: http.MethodGet,
+			url:    "https://example.com/a",
+			want:   want{body: "404 page not found\n", statusCode: http.StatusNotFound},
+		},
+		{
+			name: "MethodNotAllowed",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					w.Write([]byte("custom " + w.Header().Get("Allow")))
+				})
+				r.Get("/a", h("a"))
+				return r
+			},
+			method: http.MethodPost,
+			url:    "https://example.com/a",
+			want:   want{body: "custom GET, HEAD", statusCode: http.StatusMethodNotAllowed},
+		},
+		{
+			name: "GET matches HEAD unlike chi",
+			router: func() http.Handler {
+				r := chicompat.NewRouter()
+				r.Get("/a", h("a"))
+				return r
+			},
+			method: http.MethodHead,
+			url:    "https://example.com/a",
+			want:   want{body: "a /a ", statusCode: http.StatusOK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.url, nil)
+			tt.router().ServeHTTP(w, r)
+			if got := w.Body.String(); got != tt.want.body {
+				t.Errorf("Body got: %q want: %q", got, tt.want.body)
+			}
+			if got := w.Result().StatusCode; got != tt.want.statusCode {
+				t.Errorf("StatusCode got: %v want: %v", got, tt.want.statusCode)
+			}
+		})
+	}
+}
+
+func TestMux_Streaming(t *testing.T) {
+	r := chicompat.NewRouter()
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatalf("http.Flusher got: %T want: implemented", w)
+		}
+		w.Write([]byte("data: 1\n\n"))
+		f.Flush()
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/events", nil))
+	if !w.Flushed {
+		t.Errorf("Flushed got: %v want: true", w.Flushed)
+	}
+}
@@ -0,0 +1,104 @@
+// Command chi2michi rewrites Go source using chi to michi.
+//
+// It performs the mechanical rewrites described in "Migrating to michi from chi" of the README:
+//   - chi.NewRouter to michi.NewRouter, chi.Router and *chi.Mux to *michi.Router
+//   - r.Get("/a", h) to r.HandleFunc("GET /a", h), and the other method functions
+//   - chi.URLParam(r, "id") to r.PathValue("id")
+//   - r.Mount("/a", h) to r.Handle("/a/", h)
+//   - "/a/" to "/a/{$}" for exact matching, and "/a/*" to "/a/{path...}"
+//
+// The method calls are rewritten only on the chi routers of the file: chi.NewRouter(), and the variables, parameters,
+// struct fields and functions of chi.Router or *chi.Mux. Calls on the other types such as http.ServeMux are kept as is.
+//
+// The constructs which cannot be converted, such as regexp path parameters and chi.RouteContext,
+// are reported with their positions.
+//
+// Usage:
+//
+//	chi2michi [-w] [-d] [path ...]
+//
+// Without -w, the rewritten source is not written. With -d, the diffs are printed.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	write = flag.Bool("w", false, "write result to source files instead of stdout")
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: chi2michi [-w] [-d] [path ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	exitCode := 0
+	for _, path := range paths {
+		// walk directories recursively like the go command
+		path = strings.TrimSuffix(path, "/...")
+		err := filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if name := d.Name(); path != "." && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+			return processFile(path)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func processFile(filename string) error {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	res, issues, err := rewrite(filename, src)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+	if bytes.Equal(src, res) {
+		return nil
+	}
+	switch {
+	case *diff:
+		fmt.Print(unifiedDiff(filename, src, res))
+	case *write:
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filename, res, info.Mode().Perm())
+	default:
+		_, err := os.Stdout.Write(res)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+const michiPath = "github.com/go-michi/michi"
+
+// chiPaths are the import paths of chi rewritten to michi. Sub packages such as chi/middleware are kept.
+var chiPaths = map[string]bool{
+	"github.com/go-chi/chi":    true,
+	"github.com/go-chi/chi/v5": true,
+}
+
+// httpMethods maps the chi method functions to the http methods
+var httpMethods = map[string]string{
+	"Connect": "CONNECT",
+	"Delete":  "DELETE",
+	"Get":     "GET",
+	"Head":    "HEAD",
+	"Options": "OPTIONS",
+	"Patch":   "PATCH",
+	"Post":    "POST",
+	"Put":     "PUT",
+	"Trace":   "TRACE",
+}
+
+// Issue is a construct which needs a review after the rewrite.
+type Issue struct {
+	Pos     token.Position
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Pos, i.Message)
+}
+
+// rewriter rewrites a file using chi to michi
+type rewriter struct {
+	fset *token.FileSet
+	// chi is the local name of the chi package
+	chi string
+	// routers are the variables and functions of chi.Router or *chi.Mux, resolved by the parser
+	routers map[*ast.Object]bool
+	// routerFields are the names of the struct fields of chi.Router or *chi.Mux
+	routerFields map[string]bool
+	issues       []Issue
+}
+
+// rewrite rewrites the source using chi to michi.
+// It returns the source as is if it does not import chi.
+func rewrite(filename string, src []byte) ([]byte, []Issue, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	spec := chiImport(file)
+	if spec == nil {
+		return src, nil, nil
+	}
+	rw := &rewriter{fset: fset, chi: "chi", routers: map[*ast.Object]bool{}, routerFields: map[string]bool{}}
+	if spec.Name != nil {
+		rw.chi = spec.Name.Name
+	}
+	// find the routers before visit rewrites their types
+	ast.Inspect(file, rw.findRouters)
+	ast.Inspect(file, rw.visit)
+
+	if rw.usesChi(file) {
+		// unconvertible constructs still use chi, so keep it and add michi
+		decl := importDecl(file, spec)
+		decl.Specs = append(decl.Specs, &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(michiPath)}})
+		if !decl.Lparen.IsValid() {
+			decl.Lparen = spec.Pos()
+			decl.Rparen = spec.End()
+		}
+	} else {
+		spec.Name = nil
+		spec.Path.Value = strconv.Quote(michiPath)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), rw.issues, nil
+}
+
+func chiImport(file *ast.File) *ast.ImportSpec {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err == nil && chiPaths[path] {
+			return spec
+		}
+	}
+	return nil
+}
+
+func importDecl(file *ast.File, spec *ast.ImportSpec) *ast.GenDecl {
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		for _, s := range decl.Specs {
+			if s == spec {
+				return decl
+			}
+		}
+	}
+	panic("chi2michi: import declaration not found")
+}
+
+func (rw *rewriter) report(pos token.Pos, format string, args ...any) {
+	rw.issues = append(rw.issues, Issue{Pos: rw.fset.Position(pos), Message: fmt.Sprintf(format, args...)})
+}
+
+// isChi reports whether expr is the selector chi.name
+func (rw *rewriter) isChi(expr ast.Expr, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == rw.chi
+}
+
+// michiRouter returns *michi.Router
+func michiRouter() ast.Expr {
+	return &ast.StarExpr{X: &ast.SelectorExpr{X: ast.NewIdent("michi"), Sel: ast.NewIdent("Router")}}
+}
+
+// routerType returns *michi.Router if expr is the type chi.Router or *chi.Mux
+func (rw *rewriter) routerType(expr ast.Expr) (ast.Expr, bool) {
+	if rw.isChi(expr, "Router") {
+		return michiRouter(), true
+	}
+	if star, ok := expr.(*ast.StarExpr); ok && rw.isChi(star.X, "Mux") {
+		return michiRouter(), true
+	}
+	return expr, false
+}
+
+// isRouterType reports whether expr is the type chi.Router or *chi.Mux
+func (rw *rewriter) isRouterType(expr ast.Expr) bool {
+	_, ok := rw.routerType(expr)
+	return ok
+}
+
+// findRouters records the variables, functions and struct fields of chi routers, in the order of the source
+func (rw *rewriter) findRouters(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.StructType:
+		for _, field := range n.Fields.List {
+			if rw.isRouterType(field.Type) {
+				for _, name := range field.Names {
+					rw.routerFields[name.Name] = true
+				}
+			}
+		}
+	case *ast.FuncType:
+		for _, list := range []*ast.FieldList{n.Params, n.Results} {
+			if list == nil {
+				continue
+			}
+			for _, field := range list.List {
+				if !rw.isRouterType(field.Type) {
+					continue
+				}
+				for _, name := range field.Names {
+					if name.Obj != nil {
+						rw.routers[name.Obj] = true
+					}
+				}
+			}
+		}
+	case *ast.FuncDecl:
+		if n.Recv == nil && n.Type.Results != nil && len(n.Type.Results.List) == 1 && rw.isRouterType(n.Type.Results.List[0].Type) {
+			rw.routers[n.Name.Obj] = true
+		}
+	case *ast.ValueSpec:
+		for i, name := range n.Names {
+			if name.Obj != nil && (n.Type != nil && rw.isRouterType(n.Type) || i < len(n.Values) && rw.isRouter(n.Values[i])) {
+				rw.routers[name.Obj] = true
+			}
+		}
+	case *ast.AssignStmt:
+		if len(n.Lhs) != len(n.Rhs) {
+			return true
+		}
+		for i, lhs := range n.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok && id.Obj != nil && rw.isRouter(n.Rhs[i]) {
+				rw.routers[id.Obj] = true
+			}
+		}
+	}
+	return true
+}
+
+// isRouter reports whether expr is a chi router: chi.NewRouter(), a recorded variable, function or struct field,
+// or With, Group and Route of a router
+func (rw *rewriter) isRouter(expr ast.Expr) bool {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return expr.Obj != nil && rw.routers[expr.Obj]
+	case *ast.SelectorExpr:
+		return rw.routerFields[expr.Sel.Name]
+	case *ast.CallExpr:
+		if rw.isChi(expr.Fun, "NewRouter") {
+			return true
+		}
+		switch fun := ast.Unparen(expr.Fun).(type) {
+		case *ast.Ident:
+			return fun.Obj != nil && rw.routers[fun.Obj]
+		case *ast.SelectorExpr:
+			switch fun.Sel.Name {
+			case "With", "Group", "Route":
+				return rw.isRouter(fun.X)
+			}
+		}
+	}
+	return false
+}
+
+func (rw *rewriter) visit(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.Field:
+		n.Type, _ = rw.routerType(n.Type)
+	case *ast.ValueSpec:
+		if n.Type != nil {
+			n.Type, _ = rw.routerType(n.Type)
+		}
+	case *ast.TypeSpec:
+		n.Type, _ = rw.routerType(n.Type)
+	case *ast.ArrayType:
+		n.Elt, _ = rw.routerType(n.Elt)
+	case *ast.MapType:
+		n.Value, _ = rw.routerType(n.Value)
+	case *ast.CallExpr:
+		rw.visitCall(n)
+	case *ast.SelectorExpr:
+		if id, ok := n.X.(*ast.Ident); ok && id.Name == rw.chi {
+			rw.report(n.Pos(), "%s.%s has no michi equivalent", rw.chi, n.Sel.Name)
+		}
+	}
+	return true
+}
+
+func (rw *rewriter) visitCall(call *ast.CallExpr) {
+	switch {
+	case rw.isChi(call.Fun, "NewRouter"):
+		call.Fun.(*ast.SelectorExpr).X = ast.NewIdent("michi")
+		return
+	case rw.isChi(call.Fun, "URLParam") && len(call.Args) == 2:
+		if key, ok := stringLit(call.Args[1]); ok && key == "*" {
+			call.Args[1] = &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("path")}
+			rw.report(call.Pos(), `chi.URLParam(r, "*") is rewritten to PathValue("path") of the {path...} wildcard`)
+		}
+		call.Fun = &ast.SelectorExpr{X: call.Args[0], Sel: ast.NewIdent("PathValue")}
+		call.Args = call.Args[1:]
+		return
+	}
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !rw.isRouter(sel.X) {
+		// the methods of other types such as http.ServeMux have the same names
+		return
+	}
+	switch name := sel.Sel.Name; name {
+	case "Get", "Post", "Put", "Delete", "Patch", "Options", "Head", "Connect", "Trace":
+		if len(call.Args) != 2 {
+			return
+		}
+		if !rw.rewritePattern(call, httpMethods[name]) {
+			return
+		}
+		sel.Sel.Name = "HandleFunc"
+	case "Method", "MethodFunc":
+		if len(call.Args) != 3 {
+			return
+		}
+		method, ok := httpMethod(call.Args[0])
+		if !ok {
+			rw.report(call.Pos(), "%s with a non constant method cannot be converted", name)
+			return
+		}
+		if p, ok := stringLit(call.Args[1]); !ok || !strings.HasPrefix(p, "/") {
+			return
+		}
+		call.Args = call.Args[1:]
+		rw.rewritePattern(call, method)
+		sel.Sel.Name = "Handle"
+		if name == "MethodFunc" {
+			sel.Sel.Name = "HandleFunc"
+		}
+	case "Handle", "HandleFunc":
+		if len(call.Args) == 2 {
+			rw.rewritePattern(call, "")
+		}
+	case "Mount":
+		if len(call.Args) != 2 {
+			return
+		}
+		p, ok := stringLit(call.Args[0])
+		if !ok || !strings.HasPrefix(p, "/") {
+			return
+		}
+		if !strings.HasSuffix(p, "/") {
+			p += "/"
+		}
+		call.Args[0] = &ast.BasicLit{ValuePos: call.Args[0].Pos(), Kind: token.STRING, Value: strconv.Quote(p)}
+		sel.Sel.Name = "Handle"
+		rw.report(call.Pos(), "Mount is rewritten to Handle(%q), the mounted handler must register full paths starting with %q", p, strings.TrimSuffix(p, "/"))
+	case "NotFound", "MethodNotAllowed":
+		if len(call.Args) == 1 {
+			rw.report(call.Pos(), "%s has no michi equivalent, register a fallback pattern such as \"/\" instead", name)
+		}
+	}
+}
+
+// rewritePattern rewrites the chi pattern of the first argument of call to the http.ServeMux pattern with the method.
+// It reports false if the first argument is not a path literal.
+func (rw *rewriter) rewritePattern(call *ast.CallExpr, method string) bool {
+	p, ok := stringLit(call.Args[0])
+	if !ok || !strings.HasPrefix(p, "/") {
+		return false
+	}
+	p = rw.convertPattern(call.Args[0].Pos(), p)
+	if method != "" {
+		p = method + " " + p
+	}
+	call.Args[0] = &ast.BasicLit{ValuePos: call.Args[0].Pos(), Kind: token.STRING, Value: strconv.Quote(p)}
+	return true
+}
+
+// convertPattern converts the chi pattern to the http.ServeMux pattern
+func (rw *rewriter) convertPattern(pos token.Pos, p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		switch {
+		case seg == "*" && i == len(segs)-1:
+			segs[i] = "{path...}"
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && strings.Contains(seg, ":"):
+			name, re, _ := strings.Cut(seg[1:len(seg)-1], ":")
+			segs[i] = "{" + name + "}"
+			rw.report(pos, "regexp %q of {%s} is dropped, validate the path value in the handler", re, name)
+		case strings.ContainsAny(seg, "{}*"):
+			if seg != "{$}" && !(strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && strings.Count(seg, "{") == 1) {
+				rw.report(pos, "segment %q cannot be converted, http.ServeMux wildcards must be a whole segment", seg)
+			}
+		}
+	}
+	p = strings.Join(segs, "/")
+	if strings.HasSuffix(p, "/") {
+		// chi matches the path exactly
+		p += "{$}"
+	}
+	return p
+}
+
+// usesChi reports whether the file still refers to the chi package
+func (rw *rewriter) usesChi(file *ast.File) bool {
+	used := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == rw.chi {
+				used = true
+			}
+		}
+		return !used
+	})
+	return used
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// httpMethod returns the method of a string literal or http.MethodXxx
+func httpMethod(expr ast.Expr) (string, bool) {
+	if s, ok := stringLit(expr); ok {
+		return strings.ToUpper(s), true
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || !strings.HasPrefix(sel.Sel.Name, "Method") {
+		return "", false
+	}
+	if id, ok := sel.X.(*ast.Ident); !ok || id.Name != "http" {
+		return "", false
+	}
+	return strings.ToUpper(strings.TrimPrefix(sel.Sel.Name, "Method")), true
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		want       string
+		wantIssues []string
+	}{
+		{
+			name: "not chi",
+			src: `package a
+
+import "net/http"
+
+func f(c *http.Client) { c.Get("/a") }
+`,
+			want: `package a
+
+import "net/http"
+
+func f(c *http.Client) { c.Get("/a") }
+`,
+		},
+		{
+			name: "router and methods",
+			src: `package a
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func routes() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", index)
+	r.Post("/users/", create)
+	r.Route("/users", func(r chi.Router) {
+		r.Get("/{id}", show)
+		r.Method(http.MethodPut, "/{id}", http.HandlerFunc(update))
+		r.MethodFunc("delete", "/{id}", remove)
+		r.Handle("/files/*", files)
+	})
+	r.Mount("/admin", admin())
+	return r
+}
+
+func admin() *chi.Mux { return chi.NewRouter() }
+
+func show(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(chi.URLParam(r, "id")))
+}
+`,
+			want: `package a
+
+import (
+	"net/http"
+
+	"github.com/go-michi/michi"
+)
+
+func routes() http.Handler {
+	r := michi.NewRouter()
+	r.HandleFunc("GET /{$}", index)
+	r.HandleFunc("POST /users/{$}", create)
+	r.Route("/users", func(r *michi.Router) {
+		r.HandleFunc("GET /{id}", show)
+		r.Handle("PUT /{id}", http.HandlerFunc(update))
+		r.HandleFunc("DELETE /{id}", remove)
+		r.Handle("/files/{path...}", files)
+	})
+	r.Handle("/admin/", admin())
+	return r
+}
+
+func admin() *michi.Router { return michi.NewRouter() }
+
+func show(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.PathValue("id")))
+}
+`,
+			wantIssues: []string{
+				`a.go:19:2: Mount is rewritten to Handle("/admin/"), the mounted handler must register full paths starting with "/admin"`,
+			},
+		},
+		{
+			name: "other receivers",
+			src: `package a
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type cache struct{}
+
+func (c *cache) Get(key string, v int) {}
+
+type server struct {
+	router chi.Router
+	mux    *http.ServeMux
+}
+
+func (s *server) routes(c *cache) {
+	http.HandleFunc("/", index)
+	s.mux.Handle("/static/", http.FileServer(http.Dir(".")))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a/", index)
+	c.Get("/key", 1)
+	s.router = chi.NewRouter()
+	s.router.Get("/a/", index)
+	s.router.With(mid).Post("/b", index)
+	var r chi.Router = chi.NewRouter()
+	r.Handle("/c/", mux)
+}
+`,
+			want: `package a
+
+import (
+	"net/http"
+
+	"github.com/go-michi/michi"
+)
+
+type cache struct{}
+
+func (c *cache) Get(key string, v int) {}
+
+type server struct {
+	router *michi.Router
+	mux    *http.ServeMux
+}
+
+func (s *server) routes(c *cache) {
+	http.HandleFunc("/", index)
+	s.mux.Handle("/static/", http.FileServer(http.Dir(".")))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a/", index)
+	c.Get("/key", 1)
+	s.router = michi.NewRouter()
+	s.router.HandleFunc("GET /a/{$}", index)
+	s.router.With(mid).HandleFunc("POST /b", index)
+	var r *michi.Router = michi.NewRouter()
+	r.Handle("/c/{$}", mux)
+}
+`,
+		},
+		{
+			name: "unconvertible",
+			src: `package a
+
+import "github.com/go-chi/chi"
+
+func routes() {
+	r := chi.NewRouter()
+	r.Get("/{id:[0-9]+}", show)
+	r.NotFound(notFound)
+	_ = chi.RouteContext(nil)
+}
+`,
+			want: `package a
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/go-michi/michi"
+)
+
+func routes() {
+	r := michi.NewRouter()
+	r.HandleFunc("GET /{id}", show)
+	r.NotFound(notFound)
+	_ = chi.RouteContext(nil)
+}
+`,
+			wantIssues: []string{
+				`a.go:7:8: regexp "[0-9]+" of {id} is dropped, validate the path value in the handler`,
+				`a.go:8:2: NotFound has no michi equivalent, register a fallback pattern such as "/" instead`,
+				`a.go:9:6: chi.RouteContext has no michi equivalent`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, issues, err := rewrite("a.go", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("rewrite got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			var gotIssues []string
+			for _, issue := range issues {
+				gotIssues = append(gotIssues, issue.String())
+			}
+			if strings.Join(gotIssues, "\n") != strings.Join(tt.wantIssues, "\n") {
+				t.Errorf("issues got:\n%s\nwant:\n%s", strings.Join(gotIssues, "\n"), strings.Join(tt.wantIssues, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	new := "a\nb\nc\nd\nE\nf\ng\nh\ni\nj\nk\n"
+	want := `--- a.go
++++ a.go
+@@ -2,9 +2,10 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+ i
+ j
++k
+`
+	if got := unifiedDiff("a.go", []byte(old), []byte(new)); got != want {
+		t.Errorf("unifiedDiff got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,303 @@
+// Package analyzer defines an Analyzer that reports mistakes in the usage of michi.Router.
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const michiPath = "github.com/go-michi/michi"
+
+// Analyzer reports mistakes in the usage of michi.Router:
+//   - Use after Handle, HandleFunc or Route on the same router, which panics at startup
+//   - With whose result is discarded, so its middlewares are never applied
+//   - Route patterns which are not a path, and Handle and HandleFunc patterns which contain double slashes
+//   - Handle and HandleFunc patterns with a host in Route, whose host is registered as a part of the path
+//   - Handle and HandleFunc patterns ending with a slash without {$}, which match the whole sub tree unlike chi
+var Analyzer = &analysis.Analyzer{
+	Name:     "michivet",
+	Doc:      "report mistakes in the usage of michi.Router",
+	URL:      "https://pkg.go.dev/github.com/go-michi/michi/cmd/michivet/analyzer",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.WithStack([]ast.Node{(*ast.BlockStmt)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			checkUseAfterRoute(pass, n)
+		case *ast.CallExpr:
+			checkPattern(pass, n, inRoute(pass, stack))
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// inRoute reports whether the node is in the function of a Route call
+func inRoute(pass *analysis.Pass, stack []ast.Node) bool {
+	for i := 1; i < len(stack); i++ {
+		lit, ok := stack[i].(*ast.FuncLit)
+		if !ok {
+			continue
+		}
+		call, ok := stack[i-1].(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 || call.Args[1] != lit {
+			continue
+		}
+		if _, method, ok := routerCall(pass, call); ok && method == "Route" {
+			return true
+		}
+	}
+	return false
+}
+
+func constantString(pass *analysis.Pass, expr ast.Expr) (string, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// routerCall returns the receiver and the method name if call is a method call on *michi.Router
+func routerCall(pass *analysis.Pass, call *ast.CallExpr) (ast.Expr, string, bool) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != michiPath {
+		return nil, "", false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil || !isRouter(recv.Type()) {
+		return nil, "", false
+	}
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil, "", false
+	}
+	return sel.X, fn.Name(), true
+}
+
+func isRouter(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == michiPath && named.Obj().Name() == "Router"
+}
+
+// checkUseAfterRoute reports Use called after Handle, HandleFunc or Route on the same router in the block,
+// and With whose result is discarded.
+func checkUseAfterRoute(pass *analysis.Pass, block *ast.BlockStmt) {
+	// firstRoutes are the first statements calling Handle, HandleFunc or Route on the router variables
+	firstRoutes := map[types.Object]ast.Stmt{}
+	for i, stmt := range block.List {
+		expr, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		call, ok := ast.Unparen(expr.X).(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		recv, method, ok := routerCall(pass, call)
+		if !ok {
+			continue
+		}
+		if method == "With" {
+			pass.Reportf(call.Pos(), "result of With is discarded, its middlewares are never applied")
+			continue
+		}
+		id, ok := ast.Unparen(recv).(*ast.Ident)
+		if !ok {
+			continue
+		}
+		obj := pass.TypesInfo.ObjectOf(id)
+		if obj == nil {
+			continue
+		}
+		switch method {
+		case "Handle", "HandleFunc", "Route":
+			if _, ok := firstRoutes[obj]; !ok {
+				firstRoutes[obj] = stmt
+			}
+		case "Use":
+			first, ok := firstRoutes[obj]
+			if !ok {
+				continue
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:     call.Pos(),
+				End:     call.End(),
+				Message: "michi: Use after Handle, HandleFunc or Route panics, all middlewares must be defined before routes",
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message: "Move Use before the first route",
+					TextEdits: []analysis.TextEdit{
+						{
+							Pos:     first.Pos(),
+							End:     first.Pos(),
+							NewText: []byte(nodeString(pass.Fset, stmt) + trailingComment(pass, stmt) + "\n" + indent(pass.Fset, first.Pos())),
+						},
+						deleteLine(pass, block.List[i-1], stmt),
+					},
+				}},
+			})
+		}
+	}
+}
+
+// checkPattern reports mistakes in the constant pattern of Route, Handle and HandleFunc.
+// route is true if the call is in the function of a Route call.
+func checkPattern(pass *analysis.Pass, call *ast.CallExpr, route bool) {
+	_, method, ok := routerCall(pass, call)
+	if !ok || len(call.Args) != 2 {
+		return
+	}
+	arg := call.Args[0]
+	pattern, ok := constantString(pass, arg)
+	if !ok {
+		return
+	}
+	lit, _ := ast.Unparen(arg).(*ast.BasicLit)
+
+	switch method {
+	case "Route":
+		if !strings.HasPrefix(pattern, "/") {
+			pass.Reportf(arg.Pos(), "Route pattern %q must be a path starting with /, methods and hosts are not supported", pattern)
+		}
+		// double slashes of Route patterns are removed when joined with the patterns of Handle
+	case "Handle", "HandleFunc":
+		path := pattern
+		if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+			path = strings.TrimLeft(pattern[i+1:], " \t")
+		}
+		if i := strings.IndexByte(path, '/'); i > 0 {
+			if route {
+				pass.Reportf(arg.Pos(), "pattern %q in Route is registered with the host %q as a part of the path, hosts are not supported in Route", pattern, path[:i])
+			}
+			path = path[i:]
+		}
+		checkDoubleSlash(pass, arg, lit, pattern)
+		if path == "/" || !strings.HasSuffix(path, "/") || isSubRouter(pass, call.Args[1]) {
+			return
+		}
+		d := analysis.Diagnostic{
+			Pos:     arg.Pos(),
+			End:     arg.End(),
+			Message: fmt.Sprintf("pattern %q matches all paths under %q, add {$} to match only %q", pattern, path, path),
+		}
+		if lit != nil {
+			d.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Add {$}",
+				TextEdits: []analysis.TextEdit{{Pos: lit.Pos(), End: lit.End(), NewText: []byte(strconv.Quote(pattern + "{$}"))}},
+			}}
+		}
+		pass.Report(d)
+	}
+}
+
+func checkDoubleSlash(pass *analysis.Pass, arg ast.Expr, lit *ast.BasicLit, pattern string) {
+	if !strings.Contains(pattern, "//") {
+		return
+	}
+	d := analysis.Diagnostic{
+		Pos:     arg.Pos(),
+		End:     arg.End(),
+		Message: fmt.Sprintf("pattern %q produces double slashes", pattern),
+	}
+	if lit != nil {
+		fixed := pattern
+		for strings.Contains(fixed, "//") {
+			fixed = strings.ReplaceAll(fixed, "//", "/")
+		}
+		d.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   "Remove double slashes",
+			TextEdits: []analysis.TextEdit{{Pos: lit.Pos(), End: lit.End(), NewText: []byte(strconv.Quote(fixed))}},
+		}}
+	}
+	pass.Report(d)
+}
+
+// isSubRouter reports whether the handler is a router mounted intentionally on the whole sub tree
+func isSubRouter(pass *analysis.Pass, handler ast.Expr) bool {
+	t := pass.TypesInfo.TypeOf(handler)
+	if t == nil {
+		return false
+	}
+	if isRouter(t) {
+		return true
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "net/http" && named.Obj().Name() == "ServeMux"
+}
+
+func nodeString(fset *token.FileSet, n ast.Node) string {
+	var buf bytes.Buffer
+	_ = format.Node(&buf, fset, n)
+	return buf.String()
+}
+
+// trailingComment returns the comments following stmt on the same line, with a leading space
+func trailingComment(pass *analysis.Pass, stmt ast.Stmt) string {
+	f := fileOf(pass, stmt.Pos())
+	if f == nil {
+		return ""
+	}
+	line := pass.Fset.Position(stmt.End()).Line
+	var comments string
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if c.Pos() >= stmt.End() && pass.Fset.Position(c.Pos()).Line == line {
+				comments += " " + c.Text
+			}
+		}
+	}
+	return comments
+}
+
+// deleteLine returns the TextEdit deleting the line of stmt including its trailing comments.
+// It deletes only stmt if the line has also prev.
+func deleteLine(pass *analysis.Pass, prev, stmt ast.Stmt) analysis.TextEdit {
+	file := pass.Fset.File(stmt.Pos())
+	line := file.Line(stmt.Pos())
+	if file.Line(prev.End()) == line || file.Line(stmt.End()) != line {
+		return analysis.TextEdit{Pos: prev.End(), End: stmt.End()}
+	}
+	end := token.Pos(file.Base() + file.Size())
+	if line < file.LineCount() {
+		end = file.LineStart(line + 1)
+	}
+	return analysis.TextEdit{Pos: file.LineStart(line), End: end}
+}
+
+func fileOf(pass *analysis.Pass, pos token.Pos) *ast.File {
+	for _, f := range pass.Files {
+		if f.FileStart <= pos && pos <= f.FileEnd {
+			return f
+		}
+	}
+	return nil
+}
+
+func indent(fset *token.FileSet, pos token.Pos) string {
+	return strings.Repeat("\t", fset.Position(pos).Column-1)
+}
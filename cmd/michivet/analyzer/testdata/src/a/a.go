@@ -0,0 +1,61 @@
+package a
+
+import (
+	"net/http"
+
+	"github.com/go-michi/michi"
+)
+
+func mid(next http.Handler) http.Handler { return next }
+
+func handler(w http.ResponseWriter, r *http.Request) {}
+
+func useAfterHandle() {
+	r := michi.NewRouter()
+	r.Use(mid)
+	r.HandleFunc("GET /a", handler)
+	r.Use(mid) // want `michi: Use after Handle, HandleFunc or Route panics`
+	r.Group(func(r *michi.Router) {
+		r.Use(mid)
+		r.HandleFunc("GET /b", handler)
+	})
+}
+
+func useAfterRoute() {
+	r := michi.NewRouter()
+	r.Route("/a", func(r *michi.Router) {
+		r.HandleFunc("GET /b", handler)
+	})
+	r.HandleFunc("GET /c", handler)
+	r.Use(mid) // want `michi: Use after Handle, HandleFunc or Route panics`
+}
+
+func discardedWith() {
+	r := michi.NewRouter()
+	r.With(mid) // want `result of With is discarded`
+	r.With(mid).HandleFunc("GET /a", handler)
+}
+
+func patterns() {
+	r := michi.NewRouter()
+	r.HandleFunc("GET /a/", handler)            // want `pattern "GET /a/" matches all paths under "/a/", add \{\$\} to match only "/a/"`
+	r.HandleFunc("GET example.com/b/", handler) // want `pattern "GET example.com/b/" matches all paths under "/b/"`
+	r.HandleFunc("GET /a/{$}", handler)
+	r.HandleFunc("GET /c/{path...}", handler)
+	r.HandleFunc("/", handler)
+	r.Handle("/d/", michi.NewRouter())
+	r.Handle("/e/", http.NewServeMux())
+	r.Route("GET /f", func(r *michi.Router) {}) // want `Route pattern "GET /f" must be a path starting with /`
+	r.Route("/g//h", func(r *michi.Router) {
+		r.HandleFunc("//i", handler) // want `pattern "//i" produces double slashes`
+	})
+}
+
+func hostPatterns() {
+	r := michi.NewRouter()
+	r.HandleFunc("GET example.com/a", handler)
+	r.Route("/b", func(r *michi.Router) {
+		r.HandleFunc("GET /c", handler)
+		r.HandleFunc("GET example.com/d", handler) // want `pattern "GET example.com/d" in Route is registered with the host "example.com" as a part of the path`
+	})
+}
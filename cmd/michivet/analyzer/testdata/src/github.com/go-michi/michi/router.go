@@ -0,0 +1,22 @@
+// Package michi is a stub of github.com/go-michi/michi for the tests.
+package michi
+
+import "net/http"
+
+type Router struct{}
+
+func NewRouter() *Router { return &Router{} }
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {}
+
+func (r *Router) Use(middlewares ...func(http.Handler) http.Handler) {}
+
+func (r *Router) With(middlewares ...func(http.Handler) http.Handler) *Router { return r }
+
+func (r *Router) Group(fn func(sub *Router)) {}
+
+func (r *Router) Route(pattern string, fn func(sub *Router)) {}
+
+func (r *Router) HandleFunc(pattern string, handlerFunc http.HandlerFunc) {}
+
+func (r *Router) Handle(pattern string, handler http.Handler) {}
@@ -0,0 +1,20 @@
+// Command michivet reports mistakes in the usage of michi.Router.
+//
+// Usage:
+//
+//	go install github.com/go-michi/michi/cmd/michivet@latest
+//	michivet ./...
+//
+// Run with -fix to apply the suggested fixes. It can also be run by go vet:
+//
+//	go vet -vettool=$(which michivet) ./...
+package main
+
+import (
+	"github.com/go-michi/michi/cmd/michivet/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,110 @@
+// Package debug provides the debug endpoints of a service: pprof, expvar, the build info,
+// the route table and the requests in flight. They can be mounted under any prefix by Route,
+// and protected by the middlewares of Options:
+//
+//	r := michi.NewRouter()
+//	inFlight := debug.NewInFlight()
+//	r.Use(inFlight.Track)
+//	r.Route("/admin/debug", debug.Routes(debug.Options{
+//		Router:      r,
+//		InFlight:    inFlight,
+//		Middlewares: []func(http.Handler) http.Handler{auth.Basic("debug", credentials)},
+//	}))
+//
+// Importing net/http/pprof and expvar registers their handlers on http.DefaultServeMux,
+// so do not serve http.DefaultServeMux publicly.
+package debug
+
+import (
+	"encoding/json"
+	"expvar"
+	"html/template"
+	"net/http"
+	"net/http/pprof"
+	rdebug "runtime/debug"
+
+	"github.com/go-michi/michi"
+)
+
+// Options are the options of Routes.
+type Options struct {
+	// Router is the router listed by /routes, not registered if nil
+	Router *michi.Router
+	// InFlight is listed by /requests, not registered if nil
+	InFlight *InFlight
+	// Middlewares are applied to all the endpoints, such as authentication
+	Middlewares []func(http.Handler) http.Handler
+}
+
+// Routes returns a function registering the endpoints for Route or Group:
+//
+//	GET  /               index of the endpoints
+//	GET  /pprof/         index of the profiles, and /pprof/{name} such as heap and goroutine
+//	GET  /pprof/cmdline, /pprof/profile, /pprof/symbol (also POST) and /pprof/trace
+//	GET  /vars           expvar
+//	GET  /build          the build info of runtime/debug.ReadBuildInfo as JSON
+//	GET  /routes         the patterns of Options.Router as JSON
+//	GET  /requests       the requests of Options.InFlight as JSON
+//
+// The pprof handlers are registered one by one, so they work under any prefix unlike http.DefaultServeMux.
+func Routes(opts Options) func(r *michi.Router) {
+	return func(r *michi.Router) {
+		r.Use(opts.Middlewares...)
+
+		r.HandleFunc("GET /pprof/{$}", pprof.Index)
+		r.HandleFunc("GET /pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("GET /pprof/profile", pprof.Profile)
+		r.HandleFunc("GET /pprof/symbol", pprof.Symbol)
+		r.HandleFunc("POST /pprof/symbol", pprof.Symbol)
+		r.HandleFunc("GET /pprof/trace", pprof.Trace)
+		r.HandleFunc("GET /pprof/{name}", func(w http.ResponseWriter, req *http.Request) {
+			pprof.Handler(req.PathValue("name")).ServeHTTP(w, req)
+		})
+		r.Handle("GET /vars", expvar.Handler())
+		r.HandleFunc("GET /build", serveBuildInfo)
+		links := []string{"pprof/", "vars", "build"}
+		if opts.Router != nil {
+			r.HandleFunc("GET /routes", func(w http.ResponseWriter, req *http.Request) {
+				writeJSON(w, http.StatusOK, opts.Router.Patterns())
+			})
+			links = append(links, "routes")
+		}
+		if opts.InFlight != nil {
+			r.HandleFunc("GET /requests", func(w http.ResponseWriter, req *http.Request) {
+				writeJSON(w, http.StatusOK, opts.InFlight.Requests())
+			})
+			links = append(links, "requests")
+		}
+		r.HandleFunc("GET /{$}", func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_ = indexTemplate.Execute(w, links)
+		})
+	}
+}
+
+// indexTemplate lists the endpoints by relative links, so that it works under any prefix
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><title>debug</title></head><body><ul>
+{{- range . }}
+<li><a href="{{ . }}">{{ . }}</a></li>
+{{- end }}
+</ul></body></html>
+`))
+
+func serveBuildInfo(w http.ResponseWriter, r *http.Request) {
+	info, ok := rdebug.ReadBuildInfo()
+	if !ok {
+		http.Error(w, "build info is not available", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,107 @@
+package debug_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/debug"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestRoutes(t *testing.T) {
+	inFlight := debug.NewInFlight()
+	requireToken := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Token") != "secret" {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	r := michi.NewRouter()
+	r.Use(middleware.RequestID, inFlight.Track)
+	r.HandleFunc("GET /items", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/admin/debug", debug.Routes(debug.Options{
+		Router:      r,
+		InFlight:    inFlight,
+		Middlewares: []func(http.Handler) http.Handler{requireToken},
+	}))
+
+	get := func(path string, token bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil)
+		if token {
+			req.Header.Set("X-Token", "secret")
+		}
+		req.Header.Set("X-Request-Id", "req-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		path       string
+		token      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{path: "/admin/debug/", token: true, wantStatus: http.StatusOK, wantBody: `<a href="pprof/">`},
+		{path: "/admin/debug/pprof/", token: false, wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized"},
+		{path: "/admin/debug/pprof/", token: true, wantStatus: http.StatusOK, wantBody: "goroutine?debug=1"},
+		{path: "/admin/debug/pprof/goroutine?debug=1", token: true, wantStatus: http.StatusOK, wantBody: "goroutine profile"},
+		{path: "/admin/debug/pprof/unknown", token: true, wantStatus: http.StatusNotFound, wantBody: "Unknown profile"},
+		{path: "/admin/debug/pprof/cmdline", token: true, wantStatus: http.StatusOK, wantBody: "debug.test"},
+		{path: "/admin/debug/vars", token: true, wantStatus: http.StatusOK, wantBody: `"memstats"`},
+		{path: "/admin/debug/build", token: true, wantStatus: http.StatusOK, wantBody: `"GoVersion"`},
+		{path: "/admin/debug/routes", token: true, wantStatus: http.StatusOK, wantBody: `"GET /admin/debug/routes"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := get(tt.path, tt.token)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body got: %v want: containing %v", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	// the request listing itself is in flight
+	w := get("/admin/debug/requests", true)
+	var requests []map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &requests); err != nil {
+		t.Fatalf("requests got: %v err: %v", w.Body.String(), err)
+	}
+	if len(requests) != 1 || requests[0]["path"] != "/admin/debug/requests" || requests[0]["requestId"] != "req-1" || requests[0]["duration"] == "" {
+		t.Errorf("requests got: %v want: the request of /admin/debug/requests", requests)
+	}
+	if got := inFlight.Requests(); len(got) != 0 {
+		t.Errorf("requests after the response got: %v want: none", got)
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(get("/admin/debug/routes", true).Body.Bytes(), &patterns); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(patterns, "GET /items") {
+		t.Errorf("routes got: %v want: containing GET /items", patterns)
+	}
+}
+
+func TestRoutes_Optional(t *testing.T) {
+	r := michi.NewRouter()
+	r.Group(debug.Routes(debug.Options{}))
+	for path, want := range map[string]int{"/routes": http.StatusNotFound, "/requests": http.StatusNotFound, "/vars": http.StatusOK} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil))
+		if w.Code != want {
+			t.Errorf("%v status got: %v want: %v", path, w.Code, want)
+		}
+	}
+}
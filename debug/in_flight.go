@@ -0,0 +1,84 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-michi/michi/middleware"
+)
+
+// InFlight tracks the requests being served.
+type InFlight struct {
+	mu       sync.Mutex
+	nextID   uint64
+	requests map[uint64]Request
+}
+
+// Request is a request in flight.
+type Request struct {
+	Method    string    `json:"method"`
+	Host      string    `json:"host"`
+	Path      string    `json:"path"`
+	Remote    string    `json:"remote"`
+	RequestID string    `json:"requestId,omitempty"`
+	Start     time.Time `json:"start"`
+	// Duration is the time since Start, it is encoded as a string such as "1.5s"
+	Duration time.Duration `json:"-"`
+}
+
+// MarshalJSON encodes Duration as a string
+func (r Request) MarshalJSON() ([]byte, error) {
+	type request Request
+	return json.Marshal(struct {
+		request
+		Duration string `json:"duration"`
+	}{request: request(r), Duration: r.Duration.String()})
+}
+
+// NewInFlight returns an empty InFlight.
+func NewInFlight() *InFlight {
+	return &InFlight{requests: map[uint64]Request{}}
+}
+
+// Track is a middleware tracking the requests until the handler returns.
+// Add it after middleware.RequestID to list the request IDs.
+func (f *InFlight) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := Request{
+			Method:    r.Method,
+			Host:      r.Host,
+			Path:      r.URL.Path,
+			Remote:    r.RemoteAddr,
+			RequestID: middleware.GetRequestID(r.Context()),
+			Start:     time.Now(),
+		}
+		f.mu.Lock()
+		id := f.nextID
+		f.nextID++
+		f.requests[id] = req
+		f.mu.Unlock()
+		defer func() {
+			f.mu.Lock()
+			delete(f.requests, id)
+			f.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Requests returns the requests in flight, the oldest first.
+func (f *InFlight) Requests() []Request {
+	now := time.Now()
+	f.mu.Lock()
+	requests := make([]Request, 0, len(f.requests))
+	for _, req := range f.requests {
+		req.Duration = now.Sub(req.Start)
+		requests = append(requests, req)
+	}
+	f.mu.Unlock()
+	slices.SortFunc(requests, func(a, b Request) int { return a.Start.Compare(b.Start) })
+	return requests
+}
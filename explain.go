@@ -0,0 +1,215 @@
+package michi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+var redirectHandlerType = reflect.TypeOf(http.RedirectHandler("", http.StatusTemporaryRedirect))
+
+// Explanation explains how a Router routes a request.
+type Explanation struct {
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	// Levels are the routers visited from the root router to the innermost sub router
+	Levels []ExplainLevel `json:"levels"`
+	// Outcome is one of "matched", "redirect", "method not allowed" and "not found"
+	Outcome string `json:"outcome"`
+	// StatusCode is the status code written by http.ServeMux, it is zero when a handler is matched
+	StatusCode int `json:"statusCode,omitempty"`
+	// Location is the redirect location
+	Location string `json:"location,omitempty"`
+	// Pattern is the pattern of the matched handler
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// ExplainLevel is the routing decision of a Router.
+type ExplainLevel struct {
+	// Prefix is the path of the Router, it is empty for the root router
+	Prefix string `json:"prefix"`
+	// Candidates are the patterns registered on the Router in registration order
+	Candidates []Candidate `json:"candidates"`
+	// Selected is the pattern selected by http.ServeMux, it is empty if no pattern is selected
+	Selected string `json:"selected,omitempty"`
+}
+
+// Candidate is a pattern considered for a request.
+type Candidate struct {
+	Pattern string `json:"pattern"`
+	Matched bool   `json:"matched"`
+	// Reason is the reason why the pattern does not match the request
+	Reason string `json:"reason,omitempty"`
+	// SubRouter is true if the pattern is registered by Route
+	SubRouter bool `json:"subRouter,omitempty"`
+}
+
+// Explain explains how r routes req without executing any handler or middleware.
+//
+// The selected pattern is decided by http.ServeMux itself, so the explanation
+// always agrees with ServeHTTP unless middlewares rewrite the request.
+func (r *Router) Explain(req *http.Request) *Explanation {
+	e := &Explanation{
+		Method: req.Method,
+		Host:   requestHost(req),
+		Path:   req.URL.Path,
+	}
+	r.explain(req, e)
+	return e
+}
+
+func (r *Router) explain(req *http.Request, e *Explanation) {
+	level := ExplainLevel{Prefix: r.path}
+	for _, rt := range *r.routes {
+		c := Candidate{Pattern: rt.pattern(), SubRouter: rt.sub != nil}
+		c.Reason = mismatch(rt, req.Method, e.Host, e.Path)
+		c.Matched = c.Reason == ""
+		level.Candidates = append(level.Candidates, c)
+	}
+	h, pattern := r.serveMux.Handler(req)
+	if reflect.TypeOf(h) != redirectHandlerType {
+		for _, rt := range *r.routes {
+			if rt.pattern() != pattern {
+				continue
+			}
+			level.Selected = pattern
+			e.Levels = append(e.Levels, level)
+			if rt.sub != nil {
+				rt.sub.explain(req, e)
+				return
+			}
+			e.Outcome = "matched"
+			e.Pattern = pattern
+			return
+		}
+	}
+	e.Levels = append(e.Levels, level)
+
+	// http.ServeMux responds by itself with a redirect, 404 or 405.
+	// h is not a registered handler, so it is safe to execute.
+	w := &discardResponseWriter{header: http.Header{}}
+	h.ServeHTTP(w, req)
+	e.StatusCode = w.statusCode
+	switch {
+	case w.statusCode >= 300 && w.statusCode < 400:
+		e.Outcome = "redirect"
+		e.Location = w.header.Get("Location")
+	case w.statusCode == http.StatusMethodNotAllowed:
+		e.Outcome = "method not allowed"
+	default:
+		e.Outcome = "not found"
+	}
+}
+
+// mismatch returns the reason why rt does not match the request, or an empty string if rt matches it
+func mismatch(rt route, method, host, path string) string {
+	patternHost, patternPath := splitHostAndPath(rt.path)
+	if patternHost != "" && patternHost != host {
+		return fmt.Sprintf("host %q does not match %q", host, patternHost)
+	}
+	if reason := mismatchPath(patternPath, path); reason != "" {
+		return reason
+	}
+	if !methodMatches(rt.method, method) {
+		return fmt.Sprintf("method %s does not match %s", method, rt.method)
+	}
+	return ""
+}
+
+func requestHost(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		return req.Host
+	}
+	return host
+}
+
+// String renders the explanation as text.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s%s\n", e.Method, e.Host, e.Path)
+	for i, level := range e.Levels {
+		prefix := level.Prefix
+		if prefix == "" {
+			prefix = "(root)"
+		}
+		fmt.Fprintf(&b, "router %d %s\n", i, prefix)
+		for _, c := range level.Candidates {
+			mark := " "
+			if c.Pattern == level.Selected {
+				mark = "*"
+			}
+			fmt.Fprintf(&b, "  %s %s", mark, c.Pattern)
+			if c.SubRouter {
+				b.WriteString(" (sub router)")
+			}
+			if c.Reason != "" {
+				fmt.Fprintf(&b, ": %s", c.Reason)
+			}
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&b, "outcome: %s", e.Outcome)
+	switch {
+	case e.Pattern != "":
+		fmt.Fprintf(&b, " %s", e.Pattern)
+	case e.Location != "":
+		fmt.Fprintf(&b, " %d %s", e.StatusCode, e.Location)
+	default:
+		fmt.Fprintf(&b, " %d", e.StatusCode)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// ExplainHandler returns a http.Handler that serves requests with r as usual,
+// but responds with the Explanation instead when the request has the header.
+// The explanation is rendered as JSON if the header value is "json", otherwise as text.
+//
+// It is opt-in because the explanation exposes the route table of r.
+//
+//	http.ListenAndServe(":3000", r.ExplainHandler("X-Michi-Explain"))
+func (r *Router) ExplainHandler(header string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		value := req.Header.Get(header)
+		if value == "" {
+			r.ServeHTTP(w, req)
+			return
+		}
+		e := r.Explain(req)
+		if strings.EqualFold(value, "json") {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(e)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(e.String()))
+	})
+}
+
+// discardResponseWriter records the status code and header, and discards the body
+type discardResponseWriter struct {
+	header     http.Header
+	statusCode int
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
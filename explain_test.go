@@ -0,0 +1,189 @@
+package michi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-michi/michi"
+)
+
+func TestExplain(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("Explain must not execute handlers")
+	})
+	r := michi.NewRouter()
+	r.Handle("GET /a/{$}", h)
+	r.Handle("example.com/host", h)
+	r.Route("/b", func(r *michi.Router) {
+		r.Handle("POST /c", h)
+		r.With().Handle("/d/{id}/", h)
+	})
+	type want struct {
+		outcome    string
+		pattern    string
+		statusCode int
+		levels     int
+		reasons    map[string]string
+	}
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		want   want
+	}{
+		{
+			name:   "matched in root router",
+			method: http.MethodGet,
+			url:    "https://example.com/a/",
+			want: want{
+				outcome: "matched",
+				pattern: "GET /a/{$}",
+				levels:  1,
+				reasons: map[string]string{"GET /a/{$}": "", "/b/": `segment "a" does not match "b"`},
+			},
+		},
+		{
+			name:   "{$} does not match sub path",
+			method: http.MethodGet,
+			url:    "https://example.com/a/x",
+			want: want{
+				outcome:    "not found",
+				statusCode: http.StatusNotFound,
+				levels:     1,
+				reasons:    map[string]string{"GET /a/{$}": "{$} matches only the path ending at the trailing slash"},
+			},
+		},
+		{
+			name:   "host does not match",
+			method: http.MethodGet,
+			url:    "https://example.org/host",
+			want: want{
+				outcome:    "not found",
+				statusCode: http.StatusNotFound,
+				levels:     1,
+				reasons:    map[string]string{"example.com/host": `host "example.org" does not match "example.com"`},
+			},
+		},
+		{
+			name:   "matched in sub router",
+			method: http.MethodGet,
+			url:    "https://example.com/b/d/1/x",
+			want: want{
+				outcome: "matched",
+				pattern: "/b/d/{id}/",
+				levels:  2,
+				reasons: map[string]string{"POST /b/c": `segment "d" does not match "c"`, "/b/d/{id}/": ""},
+			},
+		},
+		{
+			name:   "method not allowed in sub router",
+			method: http.MethodGet,
+			url:    "https://example.com/b/c",
+			want: want{
+				outcome:    "method not allowed",
+				statusCode: http.StatusMethodNotAllowed,
+				levels:     2,
+				reasons:    map[string]string{"POST /b/c": "method GET does not match POST"},
+			},
+		},
+		{
+			name:   "trailing slash",
+			method: http.MethodGet,
+			url:    "https://example.com/b/c/",
+			want: want{
+				outcome:    "not found",
+				statusCode: http.StatusNotFound,
+				levels:     2,
+				reasons:    map[string]string{"POST /b/c": "unexpected trailing slash"},
+			},
+		},
+		{
+			name:   "redirect to sub router",
+			method: http.MethodGet,
+			url:    "https://example.com/b",
+			want: want{
+				outcome:    "redirect",
+				statusCode: http.StatusTemporaryRedirect,
+				levels:     1,
+				reasons:    map[string]string{"/b/": "missing trailing slash"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := r.Explain(httptest.NewRequest(tt.method, tt.url, nil))
+			if e.Outcome != tt.want.outcome {
+				t.Errorf("Outcome got: %v want: %v", e.Outcome, tt.want.outcome)
+			}
+			if e.Pattern != tt.want.pattern {
+				t.Errorf("Pattern got: %v want: %v", e.Pattern, tt.want.pattern)
+			}
+			// http.ServeMux of older Go versions redirects with 301
+			if e.StatusCode != tt.want.statusCode && !(tt.want.outcome == "redirect" && e.StatusCode == http.StatusMovedPermanently) {
+				t.Errorf("StatusCode got: %v want: %v", e.StatusCode, tt.want.statusCode)
+			}
+			if len(e.Levels) != tt.want.levels {
+				t.Fatalf("Levels got: %v want: %v", len(e.Levels), tt.want.levels)
+			}
+			candidates := map[string]michi.Candidate{}
+			for _, level := range e.Levels {
+				for _, c := range level.Candidates {
+					candidates[c.Pattern] = c
+				}
+			}
+			for pattern, reason := range tt.want.reasons {
+				c, ok := candidates[pattern]
+				if !ok {
+					t.Errorf("Candidate %v not found", pattern)
+					continue
+				}
+				if c.Reason != reason {
+					t.Errorf("Reason of %v got: %v want: %v", pattern, c.Reason, reason)
+				}
+				if c.Matched != (reason == "") {
+					t.Errorf("Matched of %v got: %v want: %v", pattern, c.Matched, reason == "")
+				}
+			}
+		})
+	}
+}
+
+func TestExplainHandler(t *testing.T) {
+	r := michi.NewRouter()
+	r.HandleFunc("GET /a", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+	})
+	h := r.ExplainHandler("X-Michi-Explain")
+	{
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/a", nil))
+		if got := w.Body.String(); got != "a" {
+			t.Errorf("Body got: %v want: %v", got, "a")
+		}
+	}
+	{
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/a", nil)
+		req.Header.Set("X-Michi-Explain", "json")
+		h.ServeHTTP(w, req)
+		var e michi.Explanation
+		if err := json.NewDecoder(w.Body).Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Pattern != "GET /a" {
+			t.Errorf("Pattern got: %v want: %v", e.Pattern, "GET /a")
+		}
+	}
+	{
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "https://example.com/a", nil)
+		req.Header.Set("X-Michi-Explain", "text")
+		h.ServeHTTP(w, req)
+		if got := w.Body.String(); !strings.Contains(got, "method POST does not match GET") || !strings.Contains(got, "outcome: method not allowed 405") {
+			t.Errorf("Body got: %v", got)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Package health provides the liveness, readiness and health endpoints of a service.
+//
+// Components register named checkers of their dependencies, and the endpoints are mounted on a michi.Router:
+//
+//	h := health.New(health.Options{})
+//	h.Register("postgres", health.CheckerFunc(db.PingContext), health.CheckOptions{Critical: true})
+//	h.Register("cache", health.CheckerFunc(cache.Ping), health.CheckOptions{})
+//	r.Group(h.Routes)
+//
+//	// on SIGTERM, fail readiness so that load balancers drain the instance, then shut down
+//	h.SetReady(false)
+//	time.Sleep(drainDelay)
+//	srv.Shutdown(ctx)
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-michi/michi"
+)
+
+// Checker checks a dependency of the service.
+type Checker interface {
+	// Check returns an error if the dependency is unavailable. It should return when ctx is done.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc is a function implementing Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Status is the status of a check or a report.
+type Status string
+
+// Statuses of checks and reports.
+const (
+	// StatusPass is all the checks passed
+	StatusPass Status = "pass"
+	// StatusWarn is some non-critical checks failed, the service is still ready
+	StatusWarn Status = "warn"
+	// StatusFail is some critical checks failed, or the service is not ready
+	StatusFail Status = "fail"
+)
+
+// Options are the options of New.
+type Options struct {
+	// Interval is the time the results of the checks are cached, default 1s.
+	// Probes of many load balancers run the checks at most once per Interval.
+	Interval time.Duration
+	// Timeout is the default timeout of the checks, default 5s
+	Timeout time.Duration
+}
+
+// CheckOptions are the options of a check.
+type CheckOptions struct {
+	// Timeout is the timeout of the check, Options.Timeout if zero
+	Timeout time.Duration
+	// Critical makes the service unhealthy and not ready if the check fails,
+	// otherwise the failure only degrades the status to StatusWarn
+	Critical bool
+	// Liveness runs the check also for /livez. A failing liveness check makes the process restarted,
+	// so use it only for the state of the process itself, not for external dependencies
+	Liveness bool
+}
+
+// Health runs the registered checks and serves the endpoints.
+type Health struct {
+	opts     Options
+	notReady atomic.Bool
+
+	mu     sync.RWMutex
+	checks []*check
+}
+
+// New returns a Health with the options. It is ready until SetReady(false).
+func New(opts Options) *Health {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	return &Health{opts: opts}
+}
+
+// Register registers the checker of name. It panics if name is already registered.
+func (h *Health) Register(name string, checker Checker, opts CheckOptions) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = h.opts.Timeout
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.checks {
+		if c.name == name {
+			panic(fmt.Sprintf("health: check %q is already registered", name))
+		}
+	}
+	h.checks = append(h.checks, &check{name: name, checker: checker, opts: opts})
+}
+
+// SetReady sets the readiness. Set false at the start of a graceful shutdown,
+// so that /readyz fails and load balancers stop sending new requests.
+func (h *Health) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
+// Ready reports whether the service is ready, regardless of the checks.
+func (h *Health) Ready() bool {
+	return !h.notReady.Load()
+}
+
+// Report is the result of the checks.
+type Report struct {
+	Status Status `json:"status"`
+	// Ready is false after SetReady(false)
+	Ready  bool                   `json:"ready"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the result of a check.
+type CheckResult struct {
+	// Status is StatusPass or StatusFail
+	Status   Status `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	// Duration is the time the check took, it is encoded as a string such as "1.5ms"
+	Duration time.Duration `json:"-"`
+	// Time is the time the check ran, it is older than the request if the result is cached
+	Time time.Time `json:"time"`
+}
+
+// MarshalJSON encodes Duration as a string
+func (c CheckResult) MarshalJSON() ([]byte, error) {
+	type result CheckResult
+	return json.Marshal(struct {
+		result
+		Duration string `json:"duration"`
+	}{result: result(c), Duration: c.Duration.String()})
+}
+
+// Liveness runs the liveness checks, and fails if any of them fails. Readiness does not affect liveness.
+func (h *Health) Liveness(ctx context.Context) Report {
+	return h.report(ctx, func(c *check) bool { return c.opts.Liveness }, true, false)
+}
+
+// Readiness runs all the checks. It fails if a critical check fails or the service is not ready.
+func (h *Health) Readiness(ctx context.Context) Report {
+	return h.report(ctx, func(c *check) bool { return true }, false, true)
+}
+
+// Check runs all the checks like Readiness, but it is not affected by SetReady.
+func (h *Health) Check(ctx context.Context) Report {
+	return h.report(ctx, func(c *check) bool { return true }, false, false)
+}
+
+// Routes registers GET /livez, /readyz and /healthz on r, responding the Report as JSON
+// with 200 OK, or 503 Service Unavailable for StatusFail.
+// /healthz is the Check report for dashboards and monitoring.
+//
+//	r.Group(h.Routes)                // /livez, /readyz and /healthz
+//	r.Route("/health", h.Routes)     // /health/livez, ...
+func (h *Health) Routes(r *michi.Router) {
+	r.HandleFunc("GET /livez", func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, h.Liveness(req.Context()))
+	})
+	r.HandleFunc("GET /readyz", func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, h.Readiness(req.Context()))
+	})
+	r.HandleFunc("GET /healthz", func(w http.ResponseWriter, req *http.Request) {
+		writeReport(w, h.Check(req.Context()))
+	})
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == StatusFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// report runs the checks selected by include in parallel.
+// If allCritical is true, any failing check fails the report.
+// If readiness is true, the report fails when the service is not ready.
+func (h *Health) report(ctx context.Context, include func(c *check) bool, allCritical, readiness bool) Report {
+	h.mu.RLock()
+	var checks []*check
+	for _, c := range h.checks {
+		if include(c) {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.result(ctx, h.opts.Interval)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Ready: h.Ready(), Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		res := results[i]
+		report.Checks[c.name] = res
+		switch {
+		case res.Status != StatusFail:
+		case res.Critical || allCritical:
+			report.Status = StatusFail
+		case report.Status == StatusPass:
+			report.Status = StatusWarn
+		}
+	}
+	if readiness && !report.Ready {
+		report.Status = StatusFail
+	}
+	return report
+}
+
+// check is a registered checker with the cached result
+type check struct {
+	name    string
+	checker Checker
+	opts    CheckOptions
+
+	// mu is held while running the check, so that concurrent requests share a run
+	mu     sync.Mutex
+	cached CheckResult
+}
+
+// result returns the cached result, or runs the check if the result is older than interval
+func (c *check) result(ctx context.Context, interval time.Duration) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.cached.Time.IsZero() && time.Since(c.cached.Time) < interval {
+		return c.cached
+	}
+	start := time.Now()
+	err := c.run(ctx)
+	c.cached = CheckResult{Status: StatusPass, Critical: c.opts.Critical, Duration: time.Since(start), Time: start}
+	if err != nil {
+		c.cached.Status = StatusFail
+		c.cached.Error = err.Error()
+	}
+	return c.cached
+}
+
+// errTimeout is the error of a check exceeding its timeout
+var errTimeout = errors.New("health: check timed out")
+
+// run runs the checker with the timeout. It returns when the timeout expires even if the checker ignores ctx.
+func (c *check) run(ctx context.Context) error {
+	// the check is shared by the requests, so it is not canceled by the request
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.opts.Timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("health: check panicked: %v", v)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errTimeout
+	}
+}
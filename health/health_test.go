@@ -0,0 +1,144 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/health"
+)
+
+func TestHealth(t *testing.T) {
+	var dbErr, cacheErr, goroutinesErr error
+	h := health.New(health.Options{Interval: time.Nanosecond})
+	h.Register("db", health.CheckerFunc(func(ctx context.Context) error { return dbErr }), health.CheckOptions{Critical: true})
+	h.Register("cache", health.CheckerFunc(func(ctx context.Context) error { return cacheErr }), health.CheckOptions{})
+	h.Register("goroutines", health.CheckerFunc(func(ctx context.Context) error { return goroutinesErr }), health.CheckOptions{Liveness: true})
+	r := michi.NewRouter()
+	r.Route("/health", h.Routes)
+
+	type want struct {
+		status     int
+		report     string
+		checks     map[string]string
+		checkError string
+	}
+	tests := []struct {
+		name                           string
+		dbErr, cacheErr, goroutinesErr error
+		notReady                       bool
+		path                           string
+		want                           want
+	}{
+		{name: "ready", path: "/health/readyz", want: want{status: http.StatusOK, report: "pass", checks: map[string]string{"db": "pass", "cache": "pass", "goroutines": "pass"}}},
+		{name: "non-critical failure", cacheErr: errors.New("cache down"), path: "/health/readyz", want: want{status: http.StatusOK, report: "warn", checks: map[string]string{"cache": "fail"}, checkError: "cache down"}},
+		{name: "critical failure", dbErr: errors.New("db down"), path: "/health/readyz", want: want{status: http.StatusServiceUnavailable, report: "fail", checks: map[string]string{"db": "fail"}, checkError: "db down"}},
+		{name: "not ready", notReady: true, path: "/health/readyz", want: want{status: http.StatusServiceUnavailable, report: "fail", checks: map[string]string{"db": "pass"}}},
+		{name: "healthz ignores readiness", notReady: true, path: "/health/healthz", want: want{status: http.StatusOK, report: "pass", checks: map[string]string{"db": "pass"}}},
+		{name: "livez runs liveness checks only", dbErr: errors.New("db down"), path: "/health/livez", want: want{status: http.StatusOK, report: "pass", checks: map[string]string{"goroutines": "pass"}}},
+		{name: "livez fails by non-critical liveness check", goroutinesErr: errors.New("too many"), notReady: true, path: "/health/livez", want: want{status: http.StatusServiceUnavailable, report: "fail", checks: map[string]string{"goroutines": "fail"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbErr, cacheErr, goroutinesErr = tt.dbErr, tt.cacheErr, tt.goroutinesErr
+			h.SetReady(!tt.notReady)
+			time.Sleep(time.Millisecond) // expire the cache
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+tt.path, nil))
+			if w.Code != tt.want.status {
+				t.Errorf("status got: %v want: %v", w.Code, tt.want.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type got: %v want: application/json", got)
+			}
+			var got struct {
+				Status string `json:"status"`
+				Ready  bool   `json:"ready"`
+				Checks map[string]struct {
+					Status   string `json:"status"`
+					Error    string `json:"error"`
+					Duration string `json:"duration"`
+				} `json:"checks"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body got: %v err: %v", w.Body.String(), err)
+			}
+			if got.Status != tt.want.report {
+				t.Errorf("report status got: %v want: %v", got.Status, tt.want.report)
+			}
+			if got.Ready != !tt.notReady {
+				t.Errorf("ready got: %v want: %v", got.Ready, !tt.notReady)
+			}
+			for name, status := range tt.want.checks {
+				c, ok := got.Checks[name]
+				if !ok || c.Status != status || c.Duration == "" {
+					t.Errorf("check %v got: %+v want: %v", name, c, status)
+				}
+				if status == "fail" && tt.want.checkError != "" && c.Error != tt.want.checkError {
+					t.Errorf("check %v error got: %v want: %v", name, c.Error, tt.want.checkError)
+				}
+			}
+			if tt.path == "/health/livez" && len(got.Checks) != 1 {
+				t.Errorf("livez checks got: %v want: goroutines only", got.Checks)
+			}
+		})
+	}
+}
+
+func TestHealth_CacheAndTimeout(t *testing.T) {
+	var runs atomic.Int32
+	block := make(chan struct{})
+	defer close(block)
+	h := health.New(health.Options{Interval: time.Hour})
+	h.Register("counted", health.CheckerFunc(func(ctx context.Context) error {
+		runs.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}), health.CheckOptions{Critical: true})
+	// ignores ctx, the result is a timeout anyway
+	h.Register("stuck", health.CheckerFunc(func(ctx context.Context) error {
+		<-block
+		return nil
+	}), health.CheckOptions{Timeout: 10 * time.Millisecond})
+	h.Register("panicking", health.CheckerFunc(func(ctx context.Context) error {
+		panic("boom")
+	}), health.CheckOptions{})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Readiness(context.Background())
+		}()
+	}
+	wg.Wait()
+	if got := runs.Load(); got != 1 {
+		t.Errorf("runs got: %v want: 1", got)
+	}
+
+	report := h.Readiness(context.Background())
+	if report.Status != health.StatusWarn {
+		t.Errorf("status got: %v want: %v", report.Status, health.StatusWarn)
+	}
+	if got := report.Checks["stuck"].Error; got != "health: check timed out" {
+		t.Errorf("stuck error got: %v want: health: check timed out", got)
+	}
+	if got := report.Checks["panicking"].Error; got != "health: check panicked: boom" {
+		t.Errorf("panicking error got: %v want: health: check panicked: boom", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register got: no panic want: panic for the duplicate name")
+		}
+	}()
+	h.Register("counted", health.CheckerFunc(func(ctx context.Context) error { return nil }), health.CheckOptions{})
+}
@@ -0,0 +1,155 @@
+package michi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintKind is the kind of LintIssue.
+type LintKind string
+
+const (
+	// LintShadowed is a route on a parent router inside the path of a sub router.
+	// http.ServeMux prefers it to the sub router, so matching requests never reach the sub router.
+	LintShadowed LintKind = "shadowed"
+	// LintUnreachable is a route in a sub router whose requests are all taken by a route on a parent router.
+	LintUnreachable LintKind = "unreachable"
+	// LintDuplicate is a pattern registered on both a parent router and a sub router.
+	// http.ServeMux panics only if the same pattern is registered twice on the same router.
+	LintDuplicate LintKind = "duplicate"
+	// LintTrailingSlash is a pair of patterns differing only by the trailing slash.
+	LintTrailingSlash LintKind = "trailing slash"
+	// LintMethodNotAllowed is a route with a method whose other methods are handled by a route
+	// without a method instead of responding 405 Method Not Allowed.
+	LintMethodNotAllowed LintKind = "method not allowed"
+)
+
+// LintIssue is a problem of the route table found by Lint.
+type LintIssue struct {
+	Kind LintKind
+	// Pattern is the pattern having the issue
+	Pattern string
+	// Related is the pattern causing the issue
+	Related string
+	Message string
+}
+
+// String returns the issue as "kind: pattern: message"
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Kind, i.Pattern, i.Message)
+}
+
+// Lint analyses the route table of r including all sub routers created by Route,
+// and reports the routes which do not behave as they look.
+//
+// It is intended to be called in tests or at startup after all routes are registered.
+//
+//	for _, issue := range r.Lint() {
+//		log.Println(issue)
+//	}
+func (r *Router) Lint() []LintIssue {
+	var issues []LintIssue
+	r.lintSubRouters(&issues)
+	r.lintMethodNotAllowed(&issues)
+
+	seen := map[string]route{}
+	for _, rt := range r.leafRoutes() {
+		host, path := splitHostAndPath(rt.path)
+		key := rt.method + " " + host + strings.TrimSuffix(strings.TrimSuffix(path, "{$}"), "/")
+		other, ok := seen[key]
+		if !ok {
+			seen[key] = rt
+			continue
+		}
+		if hasTrailingSlash(other.path) != hasTrailingSlash(rt.path) {
+			issues = append(issues, LintIssue{
+				Kind:    LintTrailingSlash,
+				Pattern: rt.pattern(),
+				Related: other.pattern(),
+				Message: fmt.Sprintf("differs from %q only by the trailing slash", other.pattern()),
+			})
+		}
+	}
+	return issues
+}
+
+// lintSubRouters reports the routes competing with the sub routers of r, then lints the sub routers recursively
+func (r *Router) lintSubRouters(issues *[]LintIssue) {
+	for _, sub := range *r.routes {
+		if sub.sub == nil {
+			continue
+		}
+		for _, rt := range *r.routes {
+			if rt.pattern() == sub.pattern() || !covers(sub, rt) {
+				continue
+			}
+			*issues = append(*issues, LintIssue{
+				Kind:    LintShadowed,
+				Pattern: rt.pattern(),
+				Related: sub.pattern(),
+				Message: fmt.Sprintf("takes precedence over sub router %q, matching requests never reach the sub router", sub.path),
+			})
+			for _, leaf := range sub.sub.leafRoutes() {
+				switch {
+				case leaf.pattern() == rt.pattern():
+					*issues = append(*issues, LintIssue{
+						Kind:    LintDuplicate,
+						Pattern: leaf.pattern(),
+						Related: rt.pattern(),
+						Message: fmt.Sprintf("is also registered on the parent router of %q, the parent one is always used", sub.path),
+					})
+				case covers(rt, leaf):
+					*issues = append(*issues, LintIssue{
+						Kind:    LintUnreachable,
+						Pattern: leaf.pattern(),
+						Related: rt.pattern(),
+						Message: fmt.Sprintf("is unreachable because %q on the parent router matches all its requests", rt.pattern()),
+					})
+				}
+			}
+		}
+		sub.sub.lintSubRouters(issues)
+	}
+}
+
+// lintMethodNotAllowed reports the routes with a method of r and its sub routers whose other methods are handled by a route without a method
+func (r *Router) lintMethodNotAllowed(issues *[]LintIssue) {
+	for _, rt := range *r.routes {
+		if rt.sub != nil {
+			rt.sub.lintMethodNotAllowed(issues)
+			continue
+		}
+		if rt.method == "" {
+			continue
+		}
+		for _, fallback := range *r.routes {
+			if fallback.sub != nil || fallback.method != "" || !covers(fallback, route{method: "", path: rt.path}) {
+				continue
+			}
+			*issues = append(*issues, LintIssue{
+				Kind:    LintMethodNotAllowed,
+				Pattern: rt.pattern(),
+				Related: fallback.pattern(),
+				Message: fmt.Sprintf("requests with other methods are handled by %q instead of 405", fallback.pattern()),
+			})
+			break
+		}
+	}
+}
+
+// covers reports whether a matches every request matched by b
+func covers(a, b route) bool {
+	aHost, aPath := splitHostAndPath(a.path)
+	bHost, bPath := splitHostAndPath(b.path)
+	if aHost != "" && aHost != bHost {
+		return false
+	}
+	if !methodMatches(a.method, b.method) {
+		return false
+	}
+	return pathCovers(aPath, bPath)
+}
+
+func hasTrailingSlash(path string) bool {
+	return strings.HasSuffix(path, "/") || strings.HasSuffix(path, "/{$}")
+}
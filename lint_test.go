@@ -0,0 +1,94 @@
+package michi_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/go-michi/michi"
+)
+
+func TestLint(t *testing.T) {
+	h := http.NotFoundHandler()
+	tests := []struct {
+		name   string
+		router func() *michi.Router
+		want   []michi.LintIssue
+	}{
+		{
+			name: "no issues",
+			router: func() *michi.Router {
+				r := michi.NewRouter()
+				r.Handle("GET /a/{$}", h)
+				r.Route("/b", func(r *michi.Router) {
+					r.Handle("GET /{id}", h)
+					r.Handle("POST /{id}", h)
+				})
+				return r
+			},
+			want: nil,
+		},
+		{
+			name: "parent route competes with sub router",
+			router: func() *michi.Router {
+				r := michi.NewRouter()
+				r.Handle("GET /a/b", h)
+				r.Route("/a", func(r *michi.Router) {
+					r.Handle("GET /b", h)
+					r.Handle("HEAD /b", h)
+					r.Handle("/b", h)
+					r.Handle("/c", h)
+				})
+				return r
+			},
+			want: []michi.LintIssue{
+				{Kind: michi.LintShadowed, Pattern: "GET /a/b", Related: "/a/"},
+				{Kind: michi.LintDuplicate, Pattern: "GET /a/b", Related: "GET /a/b"},
+				{Kind: michi.LintUnreachable, Pattern: "HEAD /a/b", Related: "GET /a/b"},
+				{Kind: michi.LintMethodNotAllowed, Pattern: "GET /a/b", Related: "/a/b"},
+				{Kind: michi.LintMethodNotAllowed, Pattern: "HEAD /a/b", Related: "/a/b"},
+			},
+		},
+		{
+			name: "trailing slash",
+			router: func() *michi.Router {
+				r := michi.NewRouter()
+				r.Handle("GET /a", h)
+				r.Group(func(r *michi.Router) {
+					r.Handle("GET /a/{$}", h)
+				})
+				r.Handle("POST /a/", h)
+				return r
+			},
+			want: []michi.LintIssue{
+				{Kind: michi.LintTrailingSlash, Pattern: "GET /a/{$}", Related: "GET /a"},
+			},
+		},
+		{
+			name: "method not allowed",
+			router: func() *michi.Router {
+				r := michi.NewRouter()
+				r.Handle("GET /a/{id}", h)
+				r.Handle("/a/", h)
+				r.Handle("example.com/b", h)
+				r.Handle("POST /b", h)
+				return r
+			},
+			want: []michi.LintIssue{
+				{Kind: michi.LintMethodNotAllowed, Pattern: "GET /a/{id}", Related: "/a/"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []michi.LintIssue
+			for _, issue := range tt.router().Lint() {
+				issue.Message = ""
+				got = append(got, issue)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint got: %v want: %v", got, tt.want)
+			}
+		})
+	}
+}
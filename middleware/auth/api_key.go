@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// APIKeyStore looks up the principal of an API key by its hash, so that the keys are not stored in plain text.
+type APIKeyStore interface {
+	// Lookup returns the principal of the key hashed by HashAPIKey, or ErrInvalidCredentials if the key is unknown
+	Lookup(ctx context.Context, hash string) (*Principal, error)
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of key to be stored in APIKeyStore.
+// API keys are random and long, so a fast hash is enough unlike passwords.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashedKeys is an APIKeyStore of the principals by the hashes of the keys.
+// The lookup of the hash does not leak the key by timing, because the hash of a guessed key is unpredictable.
+type HashedKeys map[string]*Principal
+
+// Lookup returns the principal of hash
+func (k HashedKeys) Lookup(ctx context.Context, hash string) (*Principal, error) {
+	p, ok := k[hash]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	// copy the principal, the middleware may set Scheme
+	cp := *p
+	return &cp, nil
+}
+
+// APIKeyOptions are the options of APIKey.
+type APIKeyOptions struct {
+	// Realm is the realm of the challenge
+	Realm string
+	// Header is the header of the key, "X-API-Key" if both Header and Query are empty
+	Header string
+	// Query is the query parameter of the key, the key is not read from the query if empty.
+	// Keys in URLs may be logged, prefer Header.
+	Query string
+	// Store looks up the principal of the key
+	Store APIKeyStore
+}
+
+// APIKey is a middleware authenticating the API key of the header or the query parameter.
+// The challenge is `APIKey realm="...", header="X-API-Key"`.
+func APIKey(opts APIKeyOptions) func(http.Handler) http.Handler {
+	if opts.Header == "" && opts.Query == "" {
+		opts.Header = "X-API-Key"
+	}
+	var params []string
+	if opts.Header != "" {
+		params = append(params, "header", opts.Header)
+	}
+	if opts.Query != "" {
+		params = append(params, "query", opts.Query)
+	}
+	ch := challenge("APIKey", opts.Realm, params...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var key string
+			if opts.Header != "" {
+				key = r.Header.Get(opts.Header)
+			}
+			if key == "" && opts.Query != "" {
+				key = r.URL.Query().Get(opts.Query)
+			}
+			if key == "" {
+				unauthorized(w, ch)
+				return
+			}
+			p, err := opts.Store.Lookup(r.Context(), HashAPIKey(key))
+			authenticated(w, r, next, p, err, "APIKey", ch)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware/auth"
+)
+
+func TestAPIKey(t *testing.T) {
+	keys := auth.HashedKeys{
+		auth.HashAPIKey("key-1"): {Subject: "service-1"},
+	}
+	r := michi.NewRouter()
+	r.Route("/header", func(r *michi.Router) {
+		r.Use(auth.APIKey(auth.APIKeyOptions{Realm: "api", Store: keys}))
+		r.HandleFunc("GET /", subjectHandler)
+	})
+	r.Route("/query", func(r *michi.Router) {
+		r.Use(auth.APIKey(auth.APIKeyOptions{Realm: "api", Header: "X-Key", Query: "api_key", Store: keys}))
+		r.HandleFunc("GET /", subjectHandler)
+	})
+
+	tests := []struct {
+		name          string
+		target        string
+		header        map[string]string
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+	}{
+		{name: "default header", target: "/header/", header: map[string]string{"X-API-Key": "key-1"}, wantStatus: http.StatusOK, wantBody: "APIKey service-1"},
+		{name: "unknown key", target: "/header/", header: map[string]string{"X-API-Key": "key-2"}, wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `APIKey realm="api", header="X-API-Key"`},
+		{name: "no key", target: "/header/", wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `APIKey realm="api", header="X-API-Key"`},
+		{name: "query not enabled", target: "/header/?api_key=key-1", wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `APIKey realm="api", header="X-API-Key"`},
+		{name: "custom header", target: "/query/", header: map[string]string{"X-Key": "key-1"}, wantStatus: http.StatusOK, wantBody: "APIKey service-1"},
+		{name: "query", target: "/query/?api_key=key-1", wantStatus: http.StatusOK, wantBody: "APIKey service-1"},
+		{name: "unknown query", target: "/query/?api_key=key-2", wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `APIKey realm="api", header="X-Key", query="api_key"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://example.com"+tt.target, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body got: %q want: %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate got: %v want: %v", got, tt.wantChallenge)
+			}
+		})
+	}
+	// the stored principal is not modified
+	if keys[auth.HashAPIKey("key-1")].Scheme != "" {
+		t.Errorf("stored Scheme got: %v want: empty", keys[auth.HashAPIKey("key-1")].Scheme)
+	}
+}
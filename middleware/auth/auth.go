@@ -0,0 +1,136 @@
+// Package auth provides authentication middlewares: Basic, Bearer, JWT and APIKey.
+// Each middleware places the authenticated Principal in the request context,
+// and responds 401 Unauthorized with the WWW-Authenticate challenge of its scheme.
+//
+// Different Route groups can use different schemes and realms:
+//
+//	r.Route("/admin", func(r *michi.Router) {
+//		r.Use(auth.Basic("admin", auth.StaticCredentials{"admin": password}))
+//	})
+//	r.Route("/api", func(r *michi.Router) {
+//		r.Use(auth.Bearer("api", verifier))
+//		r.With(auth.RequireScopes("write")).HandleFunc("POST /items", createItem)
+//	})
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidCredentials is returned by CredentialProvider, TokenVerifier and APIKeyStore
+// when the credentials are invalid. Other errors respond 500 Internal Server Error.
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
+// Principal is the authenticated client.
+type Principal struct {
+	// Subject identifies the client such as the user name
+	Subject string
+	// Scheme is the authentication scheme, "Basic", "Bearer" or "APIKey"
+	Scheme string
+	// Scopes are the scopes granted to the client
+	Scopes []string
+	// Claims are the other attributes of the client
+	Claims map[string]any
+}
+
+// HasScope reports whether the principal is granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx with the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// GetPrincipal returns the principal authenticated by the middlewares.
+func GetPrincipal(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// RequireScopes is a middleware responding 403 Forbidden unless the principal is granted all the scopes,
+// with the insufficient_scope challenge of RFC 6750 for Bearer.
+// It responds 401 Unauthorized with the plain Bearer challenge if the request is not authenticated,
+// so add it after the middleware authenticating the request.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := GetPrincipal(r.Context())
+			if !ok {
+				unauthenticated(w)
+				return
+			}
+			for _, scope := range scopes {
+				if !p.HasScope(scope) {
+					if p.Scheme == "Bearer" {
+						w.Header().Set("WWW-Authenticate", challenge("Bearer", "",
+							"error", "insufficient_scope", "scope", strings.Join(scopes, " ")))
+					}
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unauthenticated responds 401 Unauthorized to the request having no principal.
+// The scheme is not known without the authenticating middleware, so the challenge is the plain Bearer
+// without an error code, as RFC 6750 requires for the request without credentials.
+func unauthenticated(w http.ResponseWriter) {
+	unauthorized(w, "Bearer")
+}
+
+// challenge returns the WWW-Authenticate challenge with the realm and the auth params
+func challenge(scheme, realm string, params ...string) string {
+	var b strings.Builder
+	b.WriteString(scheme)
+	if realm != "" {
+		params = append([]string{"realm", realm}, params...)
+	}
+	for i := 0; i+1 < len(params); i += 2 {
+		if i == 0 {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(params[i])
+		b.WriteString("=")
+		b.WriteString(quote(params[i+1]))
+	}
+	return b.String()
+}
+
+// quote returns s as a quoted-string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// unauthorized responds 401 Unauthorized with the challenge
+func unauthorized(w http.ResponseWriter, challenge string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// authenticated serves next with the principal, or responds the error of authentication
+func authenticated(w http.ResponseWriter, r *http.Request, next http.Handler, p *Principal, err error, scheme, challenge string) {
+	switch {
+	case err == nil && p != nil:
+		if p.Scheme == "" {
+			p.Scheme = scheme
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	case err == nil || errors.Is(err, ErrInvalidCredentials):
+		unauthorized(w, challenge)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
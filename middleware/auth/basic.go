@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+// CredentialProvider authenticates the user name and the password of Basic.
+type CredentialProvider interface {
+	// Authenticate returns the principal, or ErrInvalidCredentials if the credentials are invalid
+	Authenticate(ctx context.Context, username, password string) (*Principal, error)
+}
+
+// CredentialProviderFunc is a function implementing CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context, username, password string) (*Principal, error)
+
+// Authenticate calls f(ctx, username, password)
+func (f CredentialProviderFunc) Authenticate(ctx context.Context, username, password string) (*Principal, error) {
+	return f(ctx, username, password)
+}
+
+// StaticCredentials is a CredentialProvider of the passwords of the users.
+// Passwords are compared in constant time, and unknown users take the same time as wrong passwords.
+type StaticCredentials map[string]string
+
+// Authenticate returns the principal of username if the password matches
+func (c StaticCredentials) Authenticate(ctx context.Context, username, password string) (*Principal, error) {
+	want, ok := c[username]
+	// compare the hashes, so that the time does not depend on the lengths
+	wantSum := sha256.Sum256([]byte(want))
+	gotSum := sha256.Sum256([]byte(password))
+	if subtle.ConstantTimeCompare(wantSum[:], gotSum[:]) != 1 || !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Subject: username, Scheme: "Basic"}, nil
+}
+
+// Basic is a middleware authenticating the Basic authentication (RFC 7617) by provider.
+// The realm is sent in the challenge, so routers protected by different credentials should have different realms.
+func Basic(realm string, provider CredentialProvider) func(http.Handler) http.Handler {
+	ch := challenge("Basic", realm, "charset", "UTF-8")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				unauthorized(w, ch)
+				return
+			}
+			p, err := provider.Authenticate(r.Context(), username, password)
+			authenticated(w, r, next, p, err, "Basic", ch)
+		})
+	}
+}
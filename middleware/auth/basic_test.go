@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware/auth"
+)
+
+// subjectHandler writes the subject and the scheme of the principal
+func subjectHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := auth.GetPrincipal(r.Context())
+	if !ok {
+		w.Write([]byte("no principal"))
+		return
+	}
+	w.Write([]byte(p.Scheme + " " + p.Subject))
+}
+
+func TestBasic(t *testing.T) {
+	failing := auth.CredentialProviderFunc(func(ctx context.Context, username, password string) (*auth.Principal, error) {
+		return nil, errors.New("database is down")
+	})
+	r := michi.NewRouter()
+	r.Route("/admin", func(r *michi.Router) {
+		r.Use(auth.Basic(`admin "area"`, auth.StaticCredentials{"alice": "secret"}))
+		r.HandleFunc("GET /", subjectHandler)
+	})
+	r.Route("/failing", func(r *michi.Router) {
+		r.Use(auth.Basic("failing", failing))
+		r.HandleFunc("GET /", subjectHandler)
+	})
+
+	tests := []struct {
+		name          string
+		path          string
+		username      string
+		password      string
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+	}{
+		{name: "valid", path: "/admin/", username: "alice", password: "secret", wantStatus: http.StatusOK, wantBody: "Basic alice"},
+		{name: "no credentials", path: "/admin/", wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `Basic realm="admin \"area\"", charset="UTF-8"`},
+		{name: "wrong password", path: "/admin/", username: "alice", password: "secreT", wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `Basic realm="admin \"area\"", charset="UTF-8"`},
+		{name: "unknown user", path: "/admin/", username: "bob", password: "", wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `Basic realm="admin \"area\"", charset="UTF-8"`},
+		{name: "provider error", path: "/failing/", username: "alice", password: "secret", wantStatus: http.StatusInternalServerError, wantBody: "Internal Server Error\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://example.com"+tt.path, nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body got: %q want: %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate got: %v want: %v", got, tt.wantChallenge)
+			}
+		})
+	}
+}
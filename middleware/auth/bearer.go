@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// TokenVerifier verifies the bearer tokens.
+type TokenVerifier interface {
+	// Verify returns the principal of the token, or an error wrapping ErrInvalidCredentials if the token is invalid.
+	// The message of the error is sent as error_description.
+	Verify(ctx context.Context, token string) (*Principal, error)
+}
+
+// TokenVerifierFunc is a function implementing TokenVerifier.
+type TokenVerifierFunc func(ctx context.Context, token string) (*Principal, error)
+
+// Verify calls f(ctx, token)
+func (f TokenVerifierFunc) Verify(ctx context.Context, token string) (*Principal, error) {
+	return f(ctx, token)
+}
+
+// BearerToken returns the token of the Authorization header with the Bearer scheme.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Bearer is a middleware authenticating the bearer token (RFC 6750) of the Authorization header by verifier.
+// The challenge has error="invalid_token" if the token is invalid.
+func Bearer(realm string, verifier TokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				unauthorized(w, challenge("Bearer", realm))
+				return
+			}
+			token, ok := BearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", challenge("Bearer", realm, "error", "invalid_request"))
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			p, err := verifier.Verify(r.Context(), token)
+			ch := challenge("Bearer", realm, "error", "invalid_token")
+			if errors.Is(err, ErrInvalidCredentials) {
+				ch = challenge("Bearer", realm, "error", "invalid_token", "error_description", err.Error())
+			}
+			authenticated(w, r, next, p, err, "Bearer", ch)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware/auth"
+)
+
+func TestBearer(t *testing.T) {
+	verifier := auth.TokenVerifierFunc(func(ctx context.Context, token string) (*auth.Principal, error) {
+		switch token {
+		case "reader":
+			return &auth.Principal{Subject: "reader", Scopes: []string{"read"}}, nil
+		case "writer":
+			return &auth.Principal{Subject: "writer", Scopes: []string{"read", "write"}}, nil
+		}
+		return nil, fmt.Errorf("%w: token expired", auth.ErrInvalidCredentials)
+	})
+	r := michi.NewRouter()
+	r.Use(auth.Bearer("api", verifier))
+	r.HandleFunc("GET /items", subjectHandler)
+	r.With(auth.RequireScopes("write")).HandleFunc("POST /items", subjectHandler)
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+	}{
+		{name: "valid", method: http.MethodGet, authorization: "Bearer reader", wantStatus: http.StatusOK, wantBody: "Bearer reader"},
+		{name: "case-insensitive scheme", method: http.MethodGet, authorization: "bearer reader", wantStatus: http.StatusOK, wantBody: "Bearer reader"},
+		{name: "no token", method: http.MethodGet, wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `Bearer realm="api"`},
+		{name: "other scheme", method: http.MethodGet, authorization: "Basic YTpi", wantStatus: http.StatusBadRequest, wantBody: "Bad Request\n", wantChallenge: `Bearer realm="api", error="invalid_request"`},
+		{name: "invalid token", method: http.MethodGet, authorization: "Bearer expired", wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n", wantChallenge: `Bearer realm="api", error="invalid_token", error_description="auth: invalid credentials: token expired"`},
+		{name: "scope granted", method: http.MethodPost, authorization: "Bearer writer", wantStatus: http.StatusOK, wantBody: "Bearer writer"},
+		{name: "insufficient scope", method: http.MethodPost, authorization: "Bearer reader", wantStatus: http.StatusForbidden, wantBody: "Forbidden\n", wantChallenge: `Bearer error="insufficient_scope", scope="write"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "https://example.com/items", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body got: %q want: %q", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate got: %v want: %v", got, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+func TestRequireScopes_Unauthenticated(t *testing.T) {
+	h := auth.RequireScopes("read")(http.HandlerFunc(subjectHandler))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status got: %v want: %v", w.Code, http.StatusUnauthorized)
+	}
+	want := "Bearer"
+	if got := w.Header().Get("WWW-Authenticate"); got != want {
+		t.Errorf("WWW-Authenticate got: %v want: %v", got, want)
+	}
+}
+
+func TestRequireScopes_OtherScheme(t *testing.T) {
+	h := auth.RequireScopes("read")(http.HandlerFunc(subjectHandler))
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: "user", Scheme: "Basic"}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status got: %v want: %v", w.Code, http.StatusForbidden)
+	}
+	// insufficient_scope is the error of Bearer
+	if got := w.Header().Get("WWW-Authenticate"); got != "" {
+		t.Errorf("WWW-Authenticate got: %v want: empty", got)
+	}
+}
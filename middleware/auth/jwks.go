@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound is returned by KeySet if the key is not found. It wraps ErrInvalidCredentials.
+var ErrKeyNotFound = fmt.Errorf("%w: key not found", ErrInvalidCredentials)
+
+// KeySet provides the keys verifying JWTs by key ID.
+// Implement it to fetch the keys from an identity provider.
+type KeySet interface {
+	// Key returns the key of kid, or ErrKeyNotFound. kid is empty if the JWT has no "kid" header.
+	Key(ctx context.Context, kid string) (JWK, error)
+}
+
+// JWK is a JSON Web Key (RFC 7517).
+type JWK struct {
+	// KeyID is the "kid" of the key
+	KeyID string
+	// Algorithm is the "alg" of the key, the key verifies any algorithm of its type if empty
+	Algorithm string
+	// Key is []byte for HS256, *rsa.PublicKey for RS256, *ecdsa.PublicKey for ES256 and ed25519.PublicKey for EdDSA
+	Key any
+}
+
+// JWKS is a JSON Web Key Set. It is a KeySet of static keys.
+type JWKS struct {
+	Keys []JWK
+}
+
+// ParseJWKS parses the JSON of a JSON Web Key Set.
+// Keys with "use" other than "sig" and keys of unsupported types are skipped.
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var raw struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("auth: parse JWKS: %w", err)
+	}
+	set := &JWKS{}
+	for _, rk := range raw.Keys {
+		if rk.Use != "" && rk.Use != "sig" {
+			continue
+		}
+		key, err := rk.key()
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("auth: parse JWKS: key %q: %w", rk.Kid, err)
+		}
+		set.Keys = append(set.Keys, JWK{KeyID: rk.Kid, Algorithm: rk.Alg, Key: key})
+	}
+	return set, nil
+}
+
+// Key returns the key of kid. If kid is empty, it returns the only key of the set.
+func (s *JWKS) Key(ctx context.Context, kid string) (JWK, error) {
+	if kid == "" {
+		if len(s.Keys) == 1 {
+			return s.Keys[0], nil
+		}
+		return JWK{}, ErrKeyNotFound
+	}
+	for _, k := range s.Keys {
+		if k.KeyID == kid {
+			return k, nil
+		}
+	}
+	return JWK{}, ErrKeyNotFound
+}
+
+// JWKSFile is a KeySet of a local JWKS file, which is reloaded when the file is modified,
+// so that keys can be rotated by adding the new key, switching the signer, then removing the old key.
+type JWKSFile struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	modTime time.Time
+	set     *JWKS
+}
+
+// NewJWKSFile loads the JWKS file of path. The modification of the file is checked at most once per interval.
+func NewJWKSFile(path string, interval time.Duration) (*JWKSFile, error) {
+	f := &JWKSFile{path: path, interval: interval}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Key returns the key of kid of the latest file.
+// If the modified file is invalid, the previous keys are used.
+func (f *JWKSFile) Key(ctx context.Context, kid string) (JWK, error) {
+	f.mu.Lock()
+	if now := time.Now(); now.Sub(f.checked) >= f.interval {
+		f.checked = now
+		if fi, err := os.Stat(f.path); err == nil && !fi.ModTime().Equal(f.modTime) {
+			_ = f.load()
+		}
+	}
+	set := f.set
+	f.mu.Unlock()
+	return set.Key(ctx, kid)
+}
+
+func (f *JWKSFile) load() error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("auth: load JWKS: %w", err)
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("auth: load JWKS: %w", err)
+	}
+	set, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+	f.set = set
+	f.modTime = fi.ModTime()
+	return nil
+}
+
+var errUnsupportedKey = errors.New("unsupported key")
+
+// rawJWK is the JSON of a JWK
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// key returns the public key or the secret of the JWK
+func (rk rawJWK) key() (any, error) {
+	switch rk.Kty {
+	case "oct":
+		k, err := decodeSegment(rk.K)
+		if err != nil || len(k) == 0 {
+			return nil, errors.New("invalid k")
+		}
+		return k, nil
+	case "RSA":
+		n, err1 := decodeSegment(rk.N)
+		e, err2 := decodeSegment(rk.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid n or e")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key is shorter than 2048 bits")
+		}
+		return key, nil
+	case "EC":
+		if rk.Crv != "P-256" {
+			return nil, errUnsupportedKey
+		}
+		x, err1 := decodeSegment(rk.X)
+		y, err2 := decodeSegment(rk.Y)
+		if err1 != nil || err2 != nil || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid x or y")
+		}
+		// parse the uncompressed point, which checks that the point is on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if rk.Crv != "Ed25519" {
+			return nil, errUnsupportedKey
+		}
+		x, err := decodeSegment(rk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid x")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errUnsupportedKey
+}
+
+// decodeSegment decodes base64url with or without the padding
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWT algorithms supported by JWTVerifier.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+// JWTOptions are the options of NewJWTVerifier.
+type JWTOptions struct {
+	// Keys provides the keys by the "kid" header, required
+	Keys KeySet
+	// Algorithms are the accepted algorithms, default HS256, RS256, ES256 and EdDSA
+	Algorithms []string
+	// Issuer is the required "iss" claim, not validated if empty
+	Issuer string
+	// Audience is the audience which the "aud" claim must contain, not validated if empty
+	Audience string
+	// Leeway is the allowed clock skew for "exp" and "nbf"
+	Leeway time.Duration
+	// Now returns the current time, default time.Now
+	Now func() time.Time
+}
+
+// JWTVerifier is a TokenVerifier of JSON Web Tokens (RFC 7519) signed by JWS compact serialization.
+// The principal has the "sub" claim as Subject, the "scope" (space separated) or "scp" claim as Scopes,
+// and all the claims as Claims. Numbers in Claims are json.Number.
+type JWTVerifier struct {
+	opts JWTOptions
+}
+
+// NewJWTVerifier returns a JWTVerifier with the options. It panics if Keys is nil.
+func NewJWTVerifier(opts JWTOptions) *JWTVerifier {
+	if opts.Keys == nil {
+		panic("auth: JWTOptions.Keys is nil")
+	}
+	if len(opts.Algorithms) == 0 {
+		opts.Algorithms = []string{HS256, RS256, ES256, EdDSA}
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &JWTVerifier{opts: opts}
+}
+
+// JWT is a middleware authenticating the bearer JWT of the Authorization header.
+// It is a shorthand of Bearer(realm, NewJWTVerifier(opts)).
+func JWT(realm string, opts JWTOptions) func(http.Handler) http.Handler {
+	return Bearer(realm, NewJWTVerifier(opts))
+}
+
+// Verify verifies the signature and the claims of token.
+// Invalid tokens return an error wrapping ErrInvalidCredentials, and errors of KeySet are returned as is.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	headerSeg, rest, ok1 := strings.Cut(token, ".")
+	payloadSeg, sigSeg, ok2 := strings.Cut(rest, ".")
+	if !ok1 || !ok2 {
+		return nil, invalidToken("malformed token")
+	}
+	var header struct {
+		Alg  string   `json:"alg"`
+		Kid  string   `json:"kid"`
+		Crit []string `json:"crit"`
+	}
+	if err := decodeJSON(headerSeg, &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	if !slices.Contains(v.opts.Algorithms, header.Alg) {
+		return nil, invalidToken("unsupported algorithm")
+	}
+	if len(header.Crit) > 0 {
+		return nil, invalidToken("unsupported critical header")
+	}
+	sig, err := decodeSegment(sigSeg)
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+	key, err := v.opts.Keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != "" && key.Algorithm != header.Alg {
+		return nil, invalidToken("algorithm does not match the key")
+	}
+	if err := verifySignature(header.Alg, key.Key, []byte(headerSeg+"."+payloadSeg), sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJSON(payloadSeg, &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	sub, _ := claims["sub"].(string)
+	return &Principal{Subject: sub, Scheme: "Bearer", Scopes: scopesOf(claims), Claims: claims}, nil
+}
+
+// validateClaims validates exp, nbf, iss and aud
+func (v *JWTVerifier) validateClaims(claims map[string]any) error {
+	now := v.opts.Now()
+	if exp, ok, err := numericDate(claims, "exp"); err != nil {
+		return err
+	} else if ok && !now.Before(exp.Add(v.opts.Leeway)) {
+		return invalidToken("token is expired")
+	}
+	if nbf, ok, err := numericDate(claims, "nbf"); err != nil {
+		return err
+	} else if ok && now.Add(v.opts.Leeway).Before(nbf) {
+		return invalidToken("token is not valid yet")
+	}
+	if v.opts.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.opts.Issuer {
+			return invalidToken("invalid issuer")
+		}
+	}
+	if v.opts.Audience != "" && !slices.Contains(stringsOf(claims["aud"]), v.opts.Audience) {
+		return invalidToken("invalid audience")
+	}
+	return nil
+}
+
+// verifySignature verifies sig of the signing input by the key of alg
+func verifySignature(alg string, key any, input, sig []byte) error {
+	errKey := invalidToken("algorithm does not match the key")
+	errSig := invalidToken("invalid signature")
+	digest := sha256.Sum256(input)
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return errKey
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errSig
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errKey
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return errSig
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().Name != "P-256" {
+			return errKey
+		}
+		// the signature is R and S of 32 bytes each, not ASN.1
+		if len(sig) != 64 {
+			return errSig
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return errSig
+		}
+	case EdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errKey
+		}
+		if !ed25519.Verify(pub, input, sig) {
+			return errSig
+		}
+	default:
+		return invalidToken("unsupported algorithm")
+	}
+	return nil
+}
+
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCredentials, reason)
+}
+
+// decodeJSON decodes the base64url JSON segment, keeping numbers as json.Number
+func decodeJSON(seg string, v any) error {
+	data, err := decodeSegment(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// numericDate returns the NumericDate claim of name
+func numericDate(claims map[string]any, name string) (time.Time, bool, error) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false, invalidToken("malformed " + name)
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsNaN(f) || math.Abs(f) > 1<<53 {
+		return time.Time{}, false, invalidToken("malformed " + name)
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), true, nil
+}
+
+// scopesOf returns the "scope" claim of RFC 8693, or the "scp" claim used by some providers
+func scopesOf(claims map[string]any) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	if scp, ok := claims["scp"].(string); ok {
+		return strings.Fields(scp)
+	}
+	return stringsOf(claims["scp"])
+}
+
+// stringsOf returns the string or the strings of the array claim
+func stringsOf(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		ss := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	}
+	return nil
+}
+
+// RequireClaim is a middleware responding 403 Forbidden unless the principal has the claim of name.
+// If values are given, the claim, or an element of the array claim, must equal one of them.
+// It responds 401 Unauthorized with the plain Bearer challenge if the request is not authenticated.
+//
+//	r.With(auth.RequireClaim("role", "admin")).HandleFunc("DELETE /items/{id}", deleteItem)
+func RequireClaim(name string, values ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := GetPrincipal(r.Context())
+			if !ok {
+				unauthenticated(w)
+				return
+			}
+			claim, ok := p.Claims[name]
+			if ok && len(values) > 0 {
+				ok = slices.ContainsFunc(stringsOf(claim), func(s string) bool {
+					return slices.Contains(values, s)
+				})
+			}
+			if !ok {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
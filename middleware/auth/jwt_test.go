@@ -0,0 +1,265 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware/auth"
+)
+
+var b64 = base64.RawURLEncoding
+
+// signJWT signs the claims by key with alg, the signer is a secret []byte or a private key
+func signJWT(t *testing.T, alg, kid string, signer any, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	var sig []byte
+	switch key := signer.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(key, []byte(input))
+	}
+	return input + "." + b64.EncodeToString(sig)
+}
+
+// jwksJSON returns the JWKS of the public keys by kid
+func jwksJSON(keys map[string]any) []byte {
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		var jwk map[string]string
+		switch key := key.(type) {
+		case []byte:
+			jwk = map[string]string{"kty": "oct", "k": b64.EncodeToString(key)}
+		case *rsa.PublicKey:
+			jwk = map[string]string{"kty": "RSA", "n": b64.EncodeToString(key.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(key.E)).Bytes())}
+		case *ecdsa.PublicKey:
+			jwk = map[string]string{"kty": "EC", "crv": "P-256", "x": b64.EncodeToString(key.X.FillBytes(make([]byte, 32))), "y": b64.EncodeToString(key.Y.FillBytes(make([]byte, 32)))}
+		case ed25519.PublicKey:
+			jwk = map[string]string{"kty": "OKP", "crv": "Ed25519", "x": b64.EncodeToString(key)}
+		}
+		jwk["kid"] = kid
+		set.Keys = append(set.Keys, jwk)
+	}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+func TestJWTVerifier(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	set, err := auth.ParseJWKS(jwksJSON(map[string]any{
+		"hs": secret, "rs": &rsaKey.PublicKey, "es": &ecKey.PublicKey, "ed": edPub,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 4 {
+		t.Fatalf("keys got: %v want: 4", len(set.Keys))
+	}
+
+	now := time.Unix(1700000000, 0)
+	verifier := auth.NewJWTVerifier(auth.JWTOptions{
+		Keys:     set,
+		Issuer:   "https://issuer.example.com",
+		Audience: "api",
+		Leeway:   30 * time.Second,
+		Now:      func() time.Time { return now },
+	})
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "alice", "iss": "https://issuer.example.com", "aud": []string{"api", "web"},
+			"exp": now.Unix() + 60, "nbf": now.Unix() - 60, "scope": "read write",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "HS256", token: signJWT(t, "HS256", "hs", secret, claims(nil))},
+		{name: "RS256", token: signJWT(t, "RS256", "rs", rsaKey, claims(nil))},
+		{name: "ES256", token: signJWT(t, "ES256", "es", ecKey, claims(nil))},
+		{name: "EdDSA", token: signJWT(t, "EdDSA", "ed", edKey, claims(nil))},
+		{name: "string audience", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"aud": "api"}))},
+		{name: "expired within leeway", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"exp": now.Unix() - 10}))},
+		{name: "expired", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"exp": now.Unix() - 30})), wantErr: "token is expired"},
+		{name: "not valid yet", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"nbf": now.Unix() + 31})), wantErr: "token is not valid yet"},
+		{name: "malformed exp", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"exp": "tomorrow"})), wantErr: "malformed exp"},
+		{name: "wrong issuer", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"iss": "https://evil.example.com"})), wantErr: "invalid issuer"},
+		{name: "wrong audience", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"aud": "web"})), wantErr: "invalid audience"},
+		{name: "no audience", token: signJWT(t, "EdDSA", "ed", edKey, claims(map[string]any{"aud": nil})), wantErr: "invalid audience"},
+		{name: "unknown kid", token: signJWT(t, "EdDSA", "unknown", edKey, claims(nil)), wantErr: "key not found"},
+		{name: "wrong key", token: signJWT(t, "ES256", "es", func() *ecdsa.PrivateKey { k, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader); return k }(), claims(nil)), wantErr: "invalid signature"},
+		{name: "algorithm confusion", token: signJWT(t, "HS256", "rs", b64.AppendEncode(nil, rsaKey.PublicKey.N.Bytes()), claims(nil)), wantErr: "algorithm does not match the key"},
+		{name: "none", token: b64.EncodeToString([]byte(`{"alg":"none"}`)) + "." + b64.EncodeToString([]byte(`{"sub":"alice"}`)) + ".", wantErr: "unsupported algorithm"},
+		{name: "malformed", token: "abc.def", wantErr: "malformed token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr != "" {
+				if !errors.Is(err, auth.ErrInvalidCredentials) || err.Error() != "auth: invalid credentials: "+tt.wantErr {
+					t.Errorf("error got: %v want: %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error got: %v want: nil", err)
+			}
+			if p.Subject != "alice" || p.Scheme != "Bearer" || !p.HasScope("read") || !p.HasScope("write") {
+				t.Errorf("principal got: %+v", p)
+			}
+		})
+	}
+}
+
+func TestJWT_RequireClaim(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	r := michi.NewRouter()
+	r.Use(auth.JWT("api", auth.JWTOptions{Keys: &auth.JWKS{Keys: []auth.JWK{{Algorithm: auth.HS256, Key: secret}}}}))
+	r.HandleFunc("GET /items", subjectHandler)
+	r.With(auth.RequireClaim("roles", "admin")).HandleFunc("DELETE /items", subjectHandler)
+	r.With(auth.RequireClaim("email_verified")).HandleFunc("POST /items", subjectHandler)
+	r.With(auth.RequireScopes("write")).HandleFunc("PUT /items", subjectHandler)
+
+	admin := signJWT(t, "HS256", "", secret, map[string]any{"sub": "admin", "roles": []string{"user", "admin"}, "scp": []string{"write"}})
+	user := signJWT(t, "HS256", "", secret, map[string]any{"sub": "user", "roles": "user", "email_verified": true})
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "authenticated", method: http.MethodGet, token: user, wantStatus: http.StatusOK, wantBody: "Bearer user"},
+		{name: "no token", method: http.MethodGet, wantStatus: http.StatusUnauthorized, wantBody: "Unauthorized\n"},
+		{name: "claim value in array", method: http.MethodDelete, token: admin, wantStatus: http.StatusOK, wantBody: "Bearer admin"},
+		{name: "claim value mismatched", method: http.MethodDelete, token: user, wantStatus: http.StatusForbidden, wantBody: "Forbidden\n"},
+		{name: "claim present", method: http.MethodPost, token: user, wantStatus: http.StatusOK, wantBody: "Bearer user"},
+		{name: "claim absent", method: http.MethodPost, token: admin, wantStatus: http.StatusForbidden, wantBody: "Forbidden\n"},
+		{name: "scp scope", method: http.MethodPut, token: admin, wantStatus: http.StatusOK, wantBody: "Bearer admin"},
+		{name: "no scope", method: http.MethodPut, token: user, wantStatus: http.StatusForbidden, wantBody: "Forbidden\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "https://example.com/items", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body got: %q want: %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRequireClaim_Unauthenticated(t *testing.T) {
+	h := auth.RequireClaim("role", "admin")(http.HandlerFunc(subjectHandler))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status got: %v want: %v", w.Code, http.StatusUnauthorized)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != "Bearer" {
+		t.Errorf("WWW-Authenticate got: %v want: %v", got, "Bearer")
+	}
+}
+
+func TestJWKSFile(t *testing.T) {
+	oldPub, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	newPub, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	write := func(keys map[string]any, modTime time.Time) {
+		if err := os.WriteFile(path, jwksJSON(keys), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Now().Add(-time.Hour)
+	write(map[string]any{"old": oldPub}, modTime)
+	keys, err := auth.NewJWKSFile(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := auth.NewJWTVerifier(auth.JWTOptions{Keys: keys})
+	verify := func(kid string, key ed25519.PrivateKey) error {
+		_, err := verifier.Verify(context.Background(), signJWT(t, "EdDSA", kid, key, map[string]any{"sub": "alice"}))
+		return err
+	}
+
+	if err := verify("old", oldKey); err != nil {
+		t.Errorf("old key got: %v want: nil", err)
+	}
+	// add the new key
+	write(map[string]any{"old": oldPub, "new": newPub}, modTime.Add(time.Minute))
+	if err := verify("new", newKey); err != nil {
+		t.Errorf("new key got: %v want: nil", err)
+	}
+	// remove the old key
+	write(map[string]any{"new": newPub}, modTime.Add(2*time.Minute))
+	if err := verify("old", oldKey); !errors.Is(err, auth.ErrKeyNotFound) {
+		t.Errorf("removed key got: %v want: %v", err, auth.ErrKeyNotFound)
+	}
+	// invalid file keeps the previous keys
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify("new", newKey); err != nil {
+		t.Errorf("invalid file got: %v want: nil", err)
+	}
+}
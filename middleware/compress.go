@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressOptions are the options of CompressWithOptions.
+type CompressOptions struct {
+	// Level is the compression level of compress/flate, flate.DefaultCompression if zero
+	Level int
+	// ContentTypes are the media types to compress, such as "application/json" or "text/*".
+	// DefaultCompressContentTypes if nil
+	ContentTypes []string
+	// MinSize is the minimum size of the response to compress in bytes, 1024 if zero
+	MinSize int
+}
+
+// DefaultCompressContentTypes are the media types compressed by default.
+var DefaultCompressContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/problem+json",
+	"application/javascript",
+	"application/xml",
+	"application/problem+xml",
+	"application/rss+xml",
+	"application/atom+xml",
+	"image/svg+xml",
+}
+
+// compressEncodings are the supported encodings in the order of preference
+var compressEncodings = []string{"gzip", "deflate"}
+
+// Compress is a middleware that compresses the response with gzip or deflate negotiated by Accept-Encoding,
+// if the response has one of DefaultCompressContentTypes and is at least 1024 bytes.
+//
+// Responses already having Content-Encoding and responses to range requests are not compressed.
+// All responses have Vary: Accept-Encoding, so that caches do not serve the uncompressed response
+// to the clients accepting gzip, or the compressed response to the others.
+// http.Flusher works: flushing before 1024 bytes are written starts the compressed stream,
+// and each flush sends the compressed data written so far.
+func Compress(next http.Handler) http.Handler {
+	return CompressWithOptions(CompressOptions{})(next)
+}
+
+// CompressWithOptions returns a Compress middleware with the options.
+func CompressWithOptions(opts CompressOptions) func(http.Handler) http.Handler {
+	if opts.Level == 0 {
+		opts.Level = flate.DefaultCompression
+	}
+	if opts.Level < flate.HuffmanOnly || opts.Level > flate.BestCompression {
+		panic("middleware: invalid compression level " + strconv.Itoa(opts.Level))
+	}
+	if opts.ContentTypes == nil {
+		opts.ContentTypes = DefaultCompressContentTypes
+	}
+	if opts.MinSize == 0 {
+		opts.MinSize = 1024
+	}
+	c := &compressor{
+		opts: opts,
+		pools: map[string]*sync.Pool{
+			"gzip": {New: func() any {
+				w, _ := gzip.NewWriterLevel(nil, opts.Level)
+				return w
+			}},
+			"deflate": {New: func() any {
+				w, _ := flate.NewWriter(nil, opts.Level)
+				return w
+			}},
+		},
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Values("Accept-Encoding"))
+			if encoding == "" || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, compressor: c, encoding: encoding, head: r.Method == http.MethodHead}
+			next.ServeHTTP(cw, r)
+			// not deferred, the buffered response must not be written if the handler panics
+			cw.close()
+		})
+	}
+}
+
+type compressor struct {
+	opts CompressOptions
+	// pools are the pools of the writers of each encoding
+	pools map[string]*sync.Pool
+}
+
+// allowed reports whether the media type of contentType is in ContentTypes
+func (c *compressor) allowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range c.opts.ContentTypes {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding returns the supported encoding with the highest q-value, or an empty string
+func negotiateEncoding(acceptEncoding []string) string {
+	qs := map[string]float64{}
+	for _, part := range splitValues(acceptEncoding) {
+		coding, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(key, "q") {
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				q = v
+			}
+		}
+		qs[strings.ToLower(strings.TrimSpace(coding))] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range compressEncodings {
+		q, ok := qs[encoding]
+		if !ok {
+			// "*" matches the codings not listed explicitly
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// encodeWriter is the writer of gzip or deflate
+type encodeWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter buffers the response up to MinSize to decide whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *compressor
+	encoding   string
+	head       bool
+	status     int
+	buf        []byte
+	decided    bool
+	enc        encodeWriter
+}
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.decided || cw.status != 0 {
+		if cw.decided {
+			cw.ResponseWriter.WriteHeader(statusCode)
+		}
+		return
+	}
+	// 1xx informational responses are followed by the final status
+	if statusCode < 200 {
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	cw.status = statusCode
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified || statusCode == http.StatusPartialContent {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.compressor.opts.MinSize {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// decide writes the header and the buffered body, compressing them if the response is compressible and large enough
+func (cw *compressWriter) decide(largeEnough bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// net/http sniffs the content type of the uncompressed body
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if largeEnough && cw.status == http.StatusOK && h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" && cw.compressor.allowed(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		// the compressed representation is not byte-for-byte identical
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		if !cw.head {
+			cw.enc = cw.compressor.pools[cw.encoding].Get().(encodeWriter)
+			cw.enc.Reset(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// Flush compresses the buffered response and flushes it to the client
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		_ = cw.decide(true)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close writes the response smaller than MinSize, or finishes the compressed stream
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// nothing is written, net/http responds as usual
+			return
+		}
+		_ = cw.decide(false)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Close()
+		cw.enc.Reset(nil)
+		cw.compressor.pools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
@@ -0,0 +1,146 @@
+package middleware_test
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("hello michi ", 200)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		rangeHeader    string
+		contentType    string
+		encoded        bool
+		body           string
+		wantEncoding   string
+	}{
+		{name: "gzip", acceptEncoding: "gzip, deflate", contentType: "application/json", body: large, wantEncoding: "gzip"},
+		{name: "q-value", acceptEncoding: "gzip;q=0.5, deflate", contentType: "text/html; charset=utf-8", body: large, wantEncoding: "deflate"},
+		{name: "wildcard", acceptEncoding: "*", contentType: "text/plain", body: large, wantEncoding: "gzip"},
+		{name: "gzip is not acceptable", acceptEncoding: "gzip;q=0, *;q=0.1", contentType: "text/plain", body: large, wantEncoding: "deflate"},
+		{name: "sniffed content type", acceptEncoding: "gzip", body: large, wantEncoding: "gzip"},
+		{name: "no Accept-Encoding", contentType: "text/plain", body: large, wantEncoding: ""},
+		{name: "not accepted", acceptEncoding: "br", contentType: "text/plain", body: large, wantEncoding: ""},
+		{name: "small", acceptEncoding: "gzip", contentType: "text/plain", body: "hello", wantEncoding: ""},
+		{name: "content type not allowed", acceptEncoding: "gzip", contentType: "image/png", body: large, wantEncoding: ""},
+		{name: "already encoded", acceptEncoding: "gzip", contentType: "text/plain", encoded: true, body: large, wantEncoding: "br"},
+		{name: "range request", acceptEncoding: "gzip", rangeHeader: "bytes=0-10", contentType: "text/plain", body: large, wantEncoding: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := middleware.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.encoded {
+					w.Header().Set("Content-Encoding", "br")
+				}
+				w.Header().Set("Content-Length", strconv.Itoa(len(tt.body)))
+				w.Header().Set("ETag", `"v1"`)
+				// write in chunks
+				for i := 0; i < len(tt.body); i += 100 {
+					w.Write([]byte(tt.body[i:min(i+100, len(tt.body))]))
+				}
+			}))
+			r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.rangeHeader != "" {
+				r.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding got: %v want: %v", got, tt.wantEncoding)
+			}
+			var body io.Reader = w.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			case "deflate":
+				body = flate.NewReader(w.Body)
+			}
+			if tt.wantEncoding == "" {
+				if got := w.Header().Get("Content-Length"); got != strconv.Itoa(len(tt.body)) {
+					t.Errorf("Content-Length got: %v want: %v", got, len(tt.body))
+				}
+			}
+			// the response depends on Accept-Encoding whether it is compressed or not
+			if got := w.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept-Encoding" {
+				t.Errorf("Vary got: %v want: %v", got, "Accept-Encoding")
+			}
+			if tt.wantEncoding == "gzip" || tt.wantEncoding == "deflate" {
+				if got := w.Header().Get("Content-Length"); got != "" {
+					t.Errorf("Content-Length got: %v want: empty", got)
+				}
+				if got := w.Header().Get("ETag"); got != `W/"v1"` {
+					t.Errorf("ETag got: %v want: %v", got, `W/"v1"`)
+				}
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.body {
+				t.Errorf("Body got: %d bytes want: %d bytes", len(got), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	flushed := make(chan struct{})
+	next := make(chan struct{})
+	h := middleware.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush got: %v", err)
+		}
+		close(flushed)
+		<-next
+		w.Write([]byte("data: 2\n\n"))
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	<-flushed
+	if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding got: %v want: gzip", got)
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first event is readable before the handler returns
+	buf := make([]byte, len("data: 1\n\n"))
+	if _, err := io.ReadFull(zr, buf); err != nil || string(buf) != "data: 1\n\n" {
+		t.Errorf("first event got: %q %v", buf, err)
+	}
+	close(next)
+	rest, _ := io.ReadAll(zr)
+	if string(rest) != "data: 2\n\n" {
+		t.Errorf("second event got: %q", rest)
+	}
+}
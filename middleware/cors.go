@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-michi/michi"
+)
+
+// CORSOptions are the options of CORSWithOptions.
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to access: exact origins such as "https://example.com",
+	// wildcard subdomains such as "https://*.example.com", or "*" for any origin
+	AllowedOrigins []string
+	// AllowOrigin allows the origins in addition to AllowedOrigins if it returns true
+	AllowOrigin func(r *http.Request, origin string) bool
+	// AllowedMethods are the methods answered to preflight requests,
+	// the methods registered on the michi.Router for the path if empty
+	AllowedMethods []string
+	// AllowedHeaders are the request headers answered to preflight requests,
+	// the headers requested by Access-Control-Request-Headers if empty
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers exposed to the scripts
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and the Authorization header
+	AllowCredentials bool
+	// MaxAge is the time the preflight responses can be cached, not sent if zero
+	MaxAge time.Duration
+}
+
+// CORS is a middleware for cross-origin resource sharing allowing the origins.
+// See CORSWithOptions.
+func CORS(origins ...string) func(http.Handler) http.Handler {
+	return CORSWithOptions(CORSOptions{AllowedOrigins: origins})
+}
+
+// CORSWithOptions returns a CORS middleware with the options.
+// It answers preflight requests with 204 No Content, so OPTIONS does not need to be registered.
+// Access-Control-Allow-Methods are the methods actually registered on the Router for the path by michi.RouteMethods,
+// so it must be added by Use of the Router, not by With.
+// It panics if "*" is allowed with credentials.
+func CORSWithOptions(opts CORSOptions) func(http.Handler) http.Handler {
+	anyOrigin := slices.Contains(opts.AllowedOrigins, "*")
+	if anyOrigin && opts.AllowCredentials {
+		panic(`middleware: CORS cannot allow "*" with credentials`)
+	}
+	allowed := func(r *http.Request, origin string) bool {
+		if anyOrigin || opts.AllowOrigin != nil && opts.AllowOrigin(r, origin) {
+			return true
+		}
+		return slices.ContainsFunc(opts.AllowedOrigins, func(pattern string) bool {
+			return matchOrigin(pattern, origin)
+		})
+	}
+	allowOrigin := func(h http.Header, origin string) {
+		if anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if opts.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+	exposed := strings.Join(opts.ExposedHeaders, ", ")
+	maxAge := strconv.FormatInt(int64(opts.MaxAge/time.Second), 10)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				h.Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+			} else {
+				h.Add("Vary", "Origin")
+			}
+			if origin == "" || !allowed(r, origin) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			allowOrigin(h, origin)
+			if !preflight {
+				if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			methods := opts.AllowedMethods
+			if len(methods) == 0 {
+				methods = michi.RouteMethods(r)
+			}
+			if len(methods) > 0 {
+				h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			}
+			if len(opts.AllowedHeaders) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// matchOrigin reports whether origin matches the exact or wildcard subdomain pattern
+func matchOrigin(pattern, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return strings.EqualFold(pattern, origin)
+	}
+	origin = strings.ToLower(origin)
+	prefix, suffix = strings.ToLower(prefix), strings.ToLower(suffix)
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	// the wildcard matches subdomain labels only, not a scheme, a port or a path
+	sub := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(sub, "/:@")
+}
@@ -0,0 +1,179 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestCORS(t *testing.T) {
+	r := michi.NewRouter()
+	r.Use(middleware.CORSWithOptions(middleware.CORSOptions{
+		AllowedOrigins: []string{"https://example.com", "https://*.example.org"},
+		AllowOrigin: func(r *http.Request, origin string) bool {
+			return strings.HasSuffix(origin, ".localhost:8080")
+		},
+		ExposedHeaders:   []string{"X-Request-Id", "RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	r.HandleFunc("GET /items", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("items"))
+	})
+	r.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/api", func(r *michi.Router) {
+		r.HandleFunc("DELETE /items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:       "simple request",
+			method:     http.MethodGet,
+			path:       "/items",
+			header:     map[string]string{"Origin": "https://example.com"},
+			wantStatus: http.StatusOK,
+			wantBody:   "items",
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-Id, RateLimit-Remaining",
+				"Access-Control-Allow-Methods":     "",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:       "wildcard subdomain",
+			method:     http.MethodGet,
+			path:       "/items",
+			header:     map[string]string{"Origin": "https://a.b.example.org"},
+			wantStatus: http.StatusOK,
+			wantBody:   "items",
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "https://a.b.example.org"},
+		},
+		{
+			name:       "wildcard does not match another port",
+			method:     http.MethodGet,
+			path:       "/items",
+			header:     map[string]string{"Origin": "https://a.example.org:8443"},
+			wantStatus: http.StatusOK,
+			wantBody:   "items",
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:       "predicate",
+			method:     http.MethodGet,
+			path:       "/items",
+			header:     map[string]string{"Origin": "http://app.localhost:8080"},
+			wantStatus: http.StatusOK,
+			wantBody:   "items",
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "http://app.localhost:8080"},
+		},
+		{
+			name:       "not allowed",
+			method:     http.MethodGet,
+			path:       "/items",
+			header:     map[string]string{"Origin": "https://evil.example.com"},
+			wantStatus: http.StatusOK,
+			wantBody:   "items",
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Expose-Headers": ""},
+		},
+		{
+			name:       "preflight with registered methods",
+			method:     http.MethodOptions,
+			path:       "/items",
+			header:     map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "content-type, x-csrf-token"},
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, HEAD, POST",
+				"Access-Control-Allow-Headers":     "content-type, x-csrf-token",
+				"Access-Control-Max-Age":           "600",
+				"Access-Control-Expose-Headers":    "",
+				"Vary":                             "Origin, Access-Control-Request-Method, Access-Control-Request-Headers",
+			},
+		},
+		{
+			name:       "preflight of sub router",
+			method:     http.MethodOptions,
+			path:       "/api/items/1",
+			header:     map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "DELETE"},
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{"Access-Control-Allow-Methods": "DELETE"},
+		},
+		{
+			name:       "preflight not allowed",
+			method:     http.MethodOptions,
+			path:       "/items",
+			header:     map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": "POST"},
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			name:       "OPTIONS without preflight",
+			method:     http.MethodOptions,
+			path:       "/items",
+			header:     map[string]string{"Origin": "https://example.com"},
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "Method Not Allowed\n",
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": "https://example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "https://api.example.com"+tt.path, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body got: %q want: %q", w.Body.String(), tt.wantBody)
+			}
+			for name, want := range tt.wantHeader {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%v got: %v want: %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	h := middleware.CORS("*")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodOptions, "https://api.example.com/items", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin got: %v want: *", got)
+	}
+	// not served by michi.Router
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Errorf("Access-Control-Allow-Methods got: %v want: empty", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("CORSWithOptions got: no panic want: panic")
+		}
+	}()
+	middleware.CORSWithOptions(middleware.CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Errors of CSRF returned by GetCSRFError.
+var (
+	ErrCSRFOrigin = errors.New("middleware: cross-origin request")
+	ErrCSRFToken  = errors.New("middleware: invalid CSRF token")
+)
+
+// CSRFOptions are the options of CSRFWithOptions.
+type CSRFOptions struct {
+	// Key signs the tokens, random per process if nil.
+	// Set a key shared by the instances to keep the tokens valid across instances and restarts.
+	Key []byte
+	// CookieName is the name of the token cookie, "__Host-csrf" by default or "csrf" if InsecureCookie
+	CookieName string
+	// InsecureCookie sends the cookie over plain HTTP, for development only
+	InsecureCookie bool
+	// HeaderName is the request header of the token, default "X-CSRF-Token"
+	HeaderName string
+	// FormField is the form field of the token, default "csrf_token"
+	FormField string
+	// TrustedOrigins are the origins such as "https://app.example.com" allowed to send cross-origin requests
+	TrustedOrigins []string
+	// ExemptPatterns are the patterns of http.ServeMux such as "POST /webhooks/{provider}" exempted from the protection
+	ExemptPatterns []string
+	// Exempt exempts the requests if it returns true
+	Exempt func(r *http.Request) bool
+	// Handler responds when the protection fails, 403 Forbidden as plain text if nil.
+	// GetCSRFError returns the reason.
+	Handler http.Handler
+}
+
+// CSRF is a middleware protecting unsafe methods from cross-site request forgery by two checks:
+// the Sec-Fetch-Site or Origin header must be same-origin (or one of TrustedOrigins),
+// where the Origin header must have the scheme, host and port of the request (resolved by RealIP behind proxies),
+// and the request must send the token of GetCSRFToken by the X-CSRF-Token header or the csrf_token form field,
+// which must match the signed cookie (the signed double-submit cookie).
+// GET, HEAD, OPTIONS and TRACE are not checked.
+//
+// The token is masked differently for every request against BREACH, put it in forms by templates:
+//
+//	<input type="hidden" name="csrf_token" value="{{ .CSRFToken }}">
+func CSRF(next http.Handler) http.Handler {
+	return CSRFWithOptions(CSRFOptions{})(next)
+}
+
+// CSRFWithOptions returns a CSRF middleware with the options.
+// It panics if ExemptPatterns has an invalid pattern.
+func CSRFWithOptions(opts CSRFOptions) func(http.Handler) http.Handler {
+	if opts.Key == nil {
+		opts.Key = make([]byte, 32)
+		randomBytes(opts.Key)
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "__Host-csrf"
+		if opts.InsecureCookie {
+			opts.CookieName = "csrf"
+		}
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+	if opts.FormField == "" {
+		opts.FormField = "csrf_token"
+	}
+	if opts.Handler == nil {
+		opts.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+	var exempt *http.ServeMux
+	if len(opts.ExemptPatterns) > 0 {
+		exempt = http.NewServeMux()
+		for _, pattern := range opts.ExemptPatterns {
+			exempt.HandleFunc(pattern, http.NotFound)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce, ok := verifyCSRFCookie(r, opts)
+			if !ok {
+				nonce = make([]byte, csrfNonceSize)
+				randomBytes(nonce)
+				http.SetCookie(w, &http.Cookie{
+					Name:     opts.CookieName,
+					Value:    signCSRFNonce(nonce, opts.Key),
+					Path:     "/",
+					Secure:   !opts.InsecureCookie,
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			ctx := context.WithValue(r.Context(), csrfTokenKey{}, nonce)
+			r = r.WithContext(ctx)
+
+			if isSafeMethod(r.Method) || opts.Exempt != nil && opts.Exempt(r) || isExempted(exempt, opts.ExemptPatterns, r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			var err error
+			switch {
+			case !sameOrigin(r, opts.TrustedOrigins):
+				err = ErrCSRFOrigin
+			case !ok || !validCSRFToken(requestCSRFToken(r, opts), nonce):
+				err = ErrCSRFToken
+			}
+			if err != nil {
+				opts.Handler.ServeHTTP(w, r.WithContext(context.WithValue(ctx, csrfErrorKey{}, err)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type csrfTokenKey struct{}
+
+type csrfErrorKey struct{}
+
+// GetCSRFToken returns the masked CSRF token for the forms and the X-CSRF-Token header,
+// or an empty string if the request is not served by CSRF.
+func GetCSRFToken(ctx context.Context) string {
+	nonce, ok := ctx.Value(csrfTokenKey{}).([]byte)
+	if !ok {
+		return ""
+	}
+	b := make([]byte, 2*csrfNonceSize)
+	mask, masked := b[:csrfNonceSize], b[csrfNonceSize:]
+	randomBytes(mask)
+	for i := range masked {
+		masked[i] = nonce[i] ^ mask[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// GetCSRFError returns ErrCSRFOrigin or ErrCSRFToken in CSRFOptions.Handler.
+func GetCSRFError(ctx context.Context) error {
+	err, _ := ctx.Value(csrfErrorKey{}).(error)
+	return err
+}
+
+const csrfNonceSize = 32
+
+// signCSRFNonce returns the cookie value of the nonce and its HMAC
+func signCSRFNonce(nonce, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(nonce)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(append([]byte{}, nonce...)))
+}
+
+// verifyCSRFCookie returns the nonce of the cookie if its signature is valid
+func verifyCSRFCookie(r *http.Request, opts CSRFOptions) ([]byte, bool) {
+	c, err := r.Cookie(opts.CookieName)
+	if err != nil {
+		return nil, false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil || len(b) != csrfNonceSize+sha256.Size {
+		return nil, false
+	}
+	nonce := b[:csrfNonceSize]
+	mac := hmac.New(sha256.New, opts.Key)
+	mac.Write(nonce)
+	if !hmac.Equal(mac.Sum(nil), b[csrfNonceSize:]) {
+		return nil, false
+	}
+	return nonce, true
+}
+
+// requestCSRFToken returns the token of the header or the form field
+func requestCSRFToken(r *http.Request, opts CSRFOptions) string {
+	if token := r.Header.Get(opts.HeaderName); token != "" {
+		return token
+	}
+	return r.PostFormValue(opts.FormField)
+}
+
+// validCSRFToken reports whether the masked token has the nonce
+func validCSRFToken(token string, nonce []byte) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != 2*csrfNonceSize {
+		return false
+	}
+	mask, masked := b[:csrfNonceSize], b[csrfNonceSize:]
+	unmasked := make([]byte, csrfNonceSize)
+	for i := range unmasked {
+		unmasked[i] = masked[i] ^ mask[i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, nonce) == 1
+}
+
+// sameOrigin reports whether the request is sent by the same origin or the trusted origins,
+// by Sec-Fetch-Site, or Origin for browsers not sending Sec-Fetch-Site.
+// Requests without both headers are not from browsers, or from old browsers, and are left to the token.
+func sameOrigin(r *http.Request, trusted []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin != "" && slices.Contains(trusted, origin) {
+		return true
+	}
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+	if origin == "" {
+		return true
+	}
+	// the origin is the scheme, host and port, an Origin of http:// is not the same origin as https://
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if info, ok := GetClientInfo(r.Context()); ok {
+		scheme, host = info.Scheme, info.Host
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Scheme, scheme) && strings.EqualFold(u.Host, host)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// isExempted reports whether r matches one of the patterns registered on the exempt ServeMux
+func isExempted(exempt *http.ServeMux, patterns []string, r *http.Request) bool {
+	if exempt == nil {
+		return false
+	}
+	// the pattern of a redirect to the path with a trailing slash is not registered
+	_, pattern := exempt.Handler(r)
+	return slices.Contains(patterns, pattern)
+}
@@ -0,0 +1,125 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestCSRF(t *testing.T) {
+	r := michi.NewRouter()
+	r.Use(middleware.CSRFWithOptions(middleware.CSRFOptions{
+		Key:            []byte("0123456789abcdef0123456789abcdef"),
+		TrustedOrigins: []string{"https://app.example.com"},
+		ExemptPatterns: []string{"POST /webhooks/{provider}"},
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, middleware.GetCSRFError(r.Context()).Error(), http.StatusForbidden)
+		}),
+	}))
+	r.HandleFunc("GET /form", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(middleware.GetCSRFToken(r.Context())))
+	})
+	r.HandleFunc("POST /form", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	r.HandleFunc("POST /webhooks/{provider}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	// get the cookie and the token
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/form", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "__Host-csrf" || !cookies[0].Secure || !cookies[0].HttpOnly {
+		t.Fatalf("cookies got: %v want: __Host-csrf", cookies)
+	}
+	cookie := cookies[0]
+	token := w.Body.String()
+
+	// tokens are masked for each request, but the cookie is kept
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/form", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() == token || w.Body.String() == "" {
+		t.Errorf("second token got: %v want: another token", w.Body.String())
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("second cookies got: %v want: none", w.Result().Cookies())
+	}
+	token2 := w.Body.String()
+
+	forged := &http.Cookie{Name: "__Host-csrf", Value: strings.Repeat("A", len(cookie.Value))}
+	tests := []struct {
+		name       string
+		path       string
+		cookie     *http.Cookie
+		header     map[string]string
+		form       url.Values
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "header token", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token}, wantStatus: http.StatusOK, wantBody: "ok"},
+		{name: "form token", path: "/form", cookie: cookie, form: url.Values{"csrf_token": {token2}}, wantStatus: http.StatusOK, wantBody: "ok"},
+		{name: "same-origin", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token, "Sec-Fetch-Site": "same-origin", "Origin": "https://example.com"}, wantStatus: http.StatusOK, wantBody: "ok"},
+		{name: "trusted origin", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token, "Sec-Fetch-Site": "same-site", "Origin": "https://app.example.com"}, wantStatus: http.StatusOK, wantBody: "ok"},
+		{name: "no token", path: "/form", cookie: cookie, wantStatus: http.StatusForbidden, wantBody: "middleware: invalid CSRF token\n"},
+		{name: "no cookie", path: "/form", header: map[string]string{"X-CSRF-Token": token}, wantStatus: http.StatusForbidden, wantBody: "middleware: invalid CSRF token\n"},
+		{name: "forged cookie", path: "/form", cookie: forged, header: map[string]string{"X-CSRF-Token": token}, wantStatus: http.StatusForbidden, wantBody: "middleware: invalid CSRF token\n"},
+		{name: "malformed token", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": "abc"}, wantStatus: http.StatusForbidden, wantBody: "middleware: invalid CSRF token\n"},
+		{name: "cross-site", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token, "Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example.com"}, wantStatus: http.StatusForbidden, wantBody: "middleware: cross-origin request\n"},
+		{name: "same origin without Sec-Fetch-Site", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token, "Origin": "https://example.com"}, wantStatus: http.StatusOK, wantBody: "ok"},
+		{name: "mismatched scheme", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token, "Origin": "http://example.com"}, wantStatus: http.StatusForbidden, wantBody: "middleware: cross-origin request\n"},
+		{name: "mismatched port", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token, "Origin": "https://example.com:8443"}, wantStatus: http.StatusForbidden, wantBody: "middleware: cross-origin request\n"},
+		{name: "cross origin without Sec-Fetch-Site", path: "/form", cookie: cookie, header: map[string]string{"X-CSRF-Token": token, "Origin": "https://evil.example.com"}, wantStatus: http.StatusForbidden, wantBody: "middleware: cross-origin request\n"},
+		{name: "exempted", path: "/webhooks/github", header: map[string]string{"Sec-Fetch-Site": "cross-site"}, wantStatus: http.StatusOK, wantBody: "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			if tt.form != nil {
+				req = httptest.NewRequest(http.MethodPost, "https://example.com"+tt.path, strings.NewReader(tt.form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(http.MethodPost, "https://example.com"+tt.path, nil)
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body got: %q want: %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCSRF_Default(t *testing.T) {
+	called := false
+	h := middleware.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "https://example.com/items/1", nil))
+	if w.Code != http.StatusForbidden || w.Body.String() != "Forbidden\n" {
+		t.Errorf("response got: %v %q want: 403 Forbidden", w.Code, w.Body.String())
+	}
+	if called {
+		t.Errorf("handler got called want: not called")
+	}
+	if err := middleware.GetCSRFError(httptest.NewRequest(http.MethodGet, "/", nil).Context()); err != nil {
+		t.Errorf("GetCSRFError without CSRF got: %v want: nil", err)
+	}
+}
@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETagOptions are the options of ETagWithOptions.
+type ETagOptions struct {
+	// Weak generates weak ETags such as W/"...", which is suitable if the same content may be encoded differently
+	Weak bool
+}
+
+// ETag is a middleware that buffers the 200 OK responses of GET and HEAD, sets a strong ETag computed from the body
+// unless the handler sets its own ETag, and evaluates the preconditions of the request:
+// 304 Not Modified for If-None-Match and If-Modified-Since (with the Last-Modified set by the handler),
+// and 412 Precondition Failed for If-Match and If-Unmodified-Since.
+//
+// HEAD is handled the same way as GET, so the handler should write the body for HEAD as for GET
+// (http.ServeMux routes HEAD to GET patterns): it is used for the ETag and Content-Length, and not sent.
+//
+// If the handler flushes the response, it is streamed without ETag.
+// For unsafe methods such as PUT, use Precondition, which evaluates the preconditions before the handler.
+func ETag(next http.Handler) http.Handler {
+	return ETagWithOptions(ETagOptions{})(next)
+}
+
+// ETagWithOptions returns an ETag middleware with the options.
+func ETagWithOptions(opts ETagOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ew := &etagWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+			if ew.streaming {
+				return
+			}
+			h := w.Header()
+			status := ew.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status == http.StatusOK && h.Get("ETag") == "" && ew.buf.Len() > 0 {
+				h.Set("ETag", computeETag(ew.buf.Bytes(), opts.Weak))
+			}
+			if status == http.StatusOK {
+				switch evaluatePreconditions(r, validatorOf(h)) {
+				case http.StatusNotModified:
+					writeNotModified(w)
+					return
+				case http.StatusPreconditionFailed:
+					http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+					return
+				}
+			}
+			if r.Method == http.MethodHead {
+				// the same headers as GET without the body
+				if h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" && ew.buf.Len() > 0 {
+					h.Set("Content-Length", strconv.Itoa(ew.buf.Len()))
+				}
+				w.WriteHeader(status)
+				return
+			}
+			if ew.status != 0 || ew.buf.Len() > 0 {
+				w.WriteHeader(status)
+			}
+			_, _ = ew.buf.WriteTo(w)
+		})
+	}
+}
+
+// computeETag returns the ETag of the body
+func computeETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	if weak {
+		etag = "W/" + etag
+	}
+	return etag
+}
+
+// writeNotModified responds 304 Not Modified without the headers describing the body
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	for _, name := range []string{"Content-Type", "Content-Length", "Transfer-Encoding"} {
+		h.Del(name)
+	}
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagWriter buffers the response until it is flushed
+type etagWriter struct {
+	http.ResponseWriter
+	status    int
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (ew *etagWriter) WriteHeader(statusCode int) {
+	if ew.streaming {
+		ew.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	// 1xx informational responses are followed by the final status
+	if statusCode < 200 {
+		ew.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if ew.status == 0 {
+		ew.status = statusCode
+	}
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if ew.streaming {
+		return ew.ResponseWriter.Write(b)
+	}
+	return ew.buf.Write(b)
+}
+
+// Flush writes the buffered response and streams the rest without ETag
+func (ew *etagWriter) Flush() {
+	if !ew.streaming {
+		ew.streaming = true
+		if ew.status != 0 {
+			ew.ResponseWriter.WriteHeader(ew.status)
+		}
+		_, _ = ew.buf.WriteTo(ew.ResponseWriter)
+	}
+	_ = http.NewResponseController(ew.ResponseWriter).Flush()
+}
+
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// Validator is the validators of the current representation of a resource.
+type Validator struct {
+	// ETag is the entity tag such as `"v1"` or `W/"v1"`
+	ETag string
+	// LastModified is the time the resource was last modified
+	LastModified time.Time
+	// Exists is false if the resource has no current representation
+	Exists bool
+}
+
+func validatorOf(h http.Header) Validator {
+	v := Validator{ETag: h.Get("ETag"), Exists: true}
+	if t, err := http.ParseTime(h.Get("Last-Modified")); err == nil {
+		v.LastModified = t
+	}
+	return v
+}
+
+// Precondition is a middleware for unsafe methods, such as PUT, PATCH and DELETE, enforcing optimistic concurrency.
+// It gets the validators of the current resource by current, and responds 412 Precondition Failed
+// without executing the handler if If-Match, If-Unmodified-Since or If-None-Match does not hold.
+// For GET and HEAD, it responds 304 Not Modified if If-None-Match or If-Modified-Since does not hold.
+// It responds 500 Internal Server Error if current returns an error.
+//
+//	r.With(middleware.Precondition(currentVersion)).HandleFunc("PUT /items/{id}", updateItem)
+func Precondition(current func(r *http.Request) (Validator, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasPreconditions(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			v, err := current(r)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			switch evaluatePreconditions(r, v) {
+			case http.StatusNotModified:
+				if v.ETag != "" {
+					w.Header().Set("ETag", v.ETag)
+				}
+				writeNotModified(w)
+			case http.StatusPreconditionFailed:
+				http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func hasPreconditions(r *http.Request) bool {
+	for _, name := range []string{"If-Match", "If-Unmodified-Since", "If-None-Match", "If-Modified-Since"} {
+		if r.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluatePreconditions evaluates the preconditions in the order of RFC 9110 Section 13.2.2,
+// and returns 304, 412 or 0 if the request should be served
+func evaluatePreconditions(r *http.Request, v Validator) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !v.Exists || !matchETag(ifMatch, v.ETag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if t, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !v.LastModified.IsZero() {
+		if v.LastModified.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if v.Exists && matchETag(ifNoneMatch, v.ETag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if t, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && safe && !v.LastModified.IsZero() {
+		if !v.LastModified.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// matchETag reports whether the list of entity tags of If-Match or If-None-Match matches etag.
+// If-None-Match uses the weak comparison, and If-Match uses the strong comparison.
+func matchETag(list, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	etagWeak, etagOpaque := splitETag(etag)
+	for list != "" {
+		var tag string
+		tag, list = nextETag(list)
+		if tag == "" {
+			continue
+		}
+		tagWeak, tagOpaque := splitETag(tag)
+		if tagOpaque == etagOpaque && (weak || !tagWeak && !etagWeak) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitETag splits the weak indicator and the opaque tag
+func splitETag(etag string) (bool, string) {
+	if opaque, ok := strings.CutPrefix(etag, "W/"); ok {
+		return true, opaque
+	}
+	return false, etag
+}
+
+// nextETag returns the first entity tag of the comma separated list and the rest, commas in quotes are not separators
+func nextETag(list string) (string, string) {
+	list = strings.TrimLeft(list, " \t,")
+	start := 0
+	if strings.HasPrefix(list, "W/") {
+		start = 2
+	}
+	if len(list) <= start || list[start] != '"' {
+		// malformed, skip to the next comma
+		_, rest, _ := strings.Cut(list, ",")
+		return "", rest
+	}
+	end := strings.IndexByte(list[start+1:], '"')
+	if end < 0 {
+		return "", ""
+	}
+	end += start + 2
+	return list[:end], list[end:]
+}
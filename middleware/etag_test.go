@@ -0,0 +1,144 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestETag(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := michi.NewRouter()
+	r.Use(middleware.ETag)
+	r.HandleFunc("GET /computed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	})
+	r.HandleFunc("GET /provided", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"v1"`)
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write([]byte("hello"))
+	})
+	r.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+		http.NewResponseController(w).Flush()
+		w.Write([]byte("b"))
+	})
+	r.HandleFunc("GET /error", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "error", http.StatusInternalServerError)
+	})
+
+	// the ETag of "hello"
+	etag := func() string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/computed", nil))
+		return w.Header().Get("ETag")
+	}()
+	if etag == "" || etag[0] != '"' {
+		t.Fatalf("ETag got: %v want: strong ETag", etag)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		wantStatus int
+		wantBody   string
+		wantETag   string
+	}{
+		{name: "no preconditions", path: "/computed", wantStatus: http.StatusOK, wantBody: "hello", wantETag: etag},
+		{name: "If-None-Match", path: "/computed", header: map[string]string{"If-None-Match": `"x", ` + etag}, wantStatus: http.StatusNotModified, wantETag: etag},
+		{name: "If-None-Match weak comparison", path: "/computed", header: map[string]string{"If-None-Match": "W/" + etag}, wantStatus: http.StatusNotModified, wantETag: etag},
+		{name: "If-None-Match not matched", path: "/computed", header: map[string]string{"If-None-Match": `"x,y"`}, wantStatus: http.StatusOK, wantBody: "hello", wantETag: etag},
+		{name: "HEAD", method: http.MethodHead, path: "/computed", wantStatus: http.StatusOK, wantETag: etag},
+		{name: "HEAD If-None-Match", method: http.MethodHead, path: "/computed", header: map[string]string{"If-None-Match": etag}, wantStatus: http.StatusNotModified, wantETag: etag},
+		{name: "HEAD If-Modified-Since", method: http.MethodHead, path: "/provided", header: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, wantStatus: http.StatusNotModified, wantETag: `W/"v1"`},
+		{name: "If-Match strong comparison", path: "/provided", header: map[string]string{"If-Match": `W/"v1"`}, wantStatus: http.StatusPreconditionFailed, wantBody: "Precondition Failed\n", wantETag: `W/"v1"`},
+		{name: "If-Modified-Since", path: "/provided", header: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, wantStatus: http.StatusNotModified, wantETag: `W/"v1"`},
+		{name: "If-Modified-Since before", path: "/provided", header: map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, wantStatus: http.StatusOK, wantBody: "hello", wantETag: `W/"v1"`},
+		{name: "If-None-Match takes precedence over If-Modified-Since", path: "/provided", header: map[string]string{"If-None-Match": `"v2"`, "If-Modified-Since": modified.Format(http.TimeFormat)}, wantStatus: http.StatusOK, wantBody: "hello", wantETag: `W/"v1"`},
+		{name: "streaming", path: "/stream", header: map[string]string{"If-None-Match": "*"}, wantStatus: http.StatusOK, wantBody: "ab"},
+		{name: "error", path: "/error", header: map[string]string{"If-None-Match": "*"}, wantStatus: http.StatusInternalServerError, wantBody: "error\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "https://example.com"+tt.path, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("StatusCode got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Body got: %q want: %q", got, tt.wantBody)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag got: %v want: %v", got, tt.wantETag)
+			}
+			if method == http.MethodHead && w.Code == http.StatusOK {
+				if got := w.Header().Get("Content-Length"); got != "5" {
+					t.Errorf("Content-Length got: %v want: %v", got, 5)
+				}
+			}
+		})
+	}
+}
+
+func TestPrecondition(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	current := func(r *http.Request) (middleware.Validator, error) {
+		switch r.PathValue("id") {
+		case "1":
+			return middleware.Validator{ETag: `"v1"`, LastModified: modified, Exists: true}, nil
+		case "error":
+			return middleware.Validator{}, errors.New("error")
+		default:
+			return middleware.Validator{}, nil
+		}
+	}
+	r := michi.NewRouter()
+	r.With(middleware.Precondition(current)).HandleFunc("PUT /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	tests := []struct {
+		name       string
+		id         string
+		header     map[string]string
+		wantStatus int
+	}{
+		{name: "no preconditions", id: "1", wantStatus: http.StatusNoContent},
+		{name: "If-Match", id: "1", header: map[string]string{"If-Match": `"v0", "v1"`}, wantStatus: http.StatusNoContent},
+		{name: "If-Match not matched", id: "1", header: map[string]string{"If-Match": `"v0"`}, wantStatus: http.StatusPreconditionFailed},
+		{name: "If-Match of missing resource", id: "2", header: map[string]string{"If-Match": "*"}, wantStatus: http.StatusPreconditionFailed},
+		{name: "If-Unmodified-Since", id: "1", header: map[string]string{"If-Unmodified-Since": modified.Format(http.TimeFormat)}, wantStatus: http.StatusNoContent},
+		{name: "If-Unmodified-Since before", id: "1", header: map[string]string{"If-Unmodified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, wantStatus: http.StatusPreconditionFailed},
+		{name: "If-None-Match * of existing resource", id: "1", header: map[string]string{"If-None-Match": "*"}, wantStatus: http.StatusPreconditionFailed},
+		{name: "If-None-Match * of missing resource", id: "2", header: map[string]string{"If-None-Match": "*"}, wantStatus: http.StatusNoContent},
+		{name: "error", id: "error", header: map[string]string{"If-Match": "*"}, wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "https://example.com/items/"+tt.id, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("StatusCode got: %v want: %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-michi/michi"
+)
+
+// LogFormat is the output format of Logger.
+type LogFormat int
+
+const (
+	// LogStructured logs each request as a slog record
+	LogStructured LogFormat = iota
+	// LogCommon writes each request in the Common Log Format
+	LogCommon
+	// LogCombined writes each request in the Combined Log Format
+	LogCombined
+)
+
+// redacted replaces the values of redacted headers and query parameters
+const redacted = "[REDACTED]"
+
+// LoggerOptions are the options of LoggerWithOptions.
+type LoggerOptions struct {
+	// Logger is the logger of LogStructured and the base of request-scoped loggers, slog.Default() if nil
+	Logger *slog.Logger
+	// Format is the output format, LogStructured by default
+	Format LogFormat
+	// Output is the writer of LogCommon and LogCombined, os.Stdout if nil
+	Output io.Writer
+	// Sample reports whether the request is logged, all requests are logged if nil
+	Sample func(r *http.Request, status int) bool
+	// Headers are the request headers included in LogStructured records
+	Headers []string
+	// RedactHeaders are the headers whose values are replaced with "[REDACTED]",
+	// Authorization, Cookie and Proxy-Authorization if nil
+	RedactHeaders []string
+	// RedactQuery are the query parameters whose values are replaced with "[REDACTED]"
+	RedactQuery []string
+}
+
+// Logger is a middleware that logs each request with slog.Default():
+// method, path, matched pattern, status, bytes, duration, remote IP and request ID.
+// Add it by Use to log the matched pattern, and after RequestID to log the request ID.
+//
+// Handlers can get the request-scoped logger by GetLogger, and enrich the log by AddLogAttrs.
+func Logger(next http.Handler) http.Handler {
+	return LoggerWithOptions(LoggerOptions{})(next)
+}
+
+// LoggerWithOptions returns a Logger middleware with the options.
+func LoggerWithOptions(opts LoggerOptions) func(http.Handler) http.Handler {
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			base := opts.Logger
+			if base == nil {
+				base = slog.Default()
+			}
+			attrs := []any{slog.String("method", r.Method), slog.String("path", r.URL.Path)}
+			if id := GetRequestID(r.Context()); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+			entry := &logEntry{logger: base.With(attrs...)}
+			ww := NewWrapResponseWriter(w)
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), logEntryKey{}, entry)))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if opts.Sample != nil && !opts.Sample(r, status) {
+				return
+			}
+			switch opts.Format {
+			case LogCommon, LogCombined:
+				writeCLF(opts.Output, opts.Format, r, opts.RedactQuery, status, ww.BytesWritten(), start)
+			default:
+				entry.log(r, &opts, status, ww.BytesWritten(), time.Since(start))
+			}
+		})
+	}
+}
+
+// SampleRate returns a LoggerOptions.Sample logging all 4xx and 5xx responses and the rate of the others.
+func SampleRate(rate float64) func(r *http.Request, status int) bool {
+	return func(r *http.Request, status int) bool {
+		return status >= 400 || rand.Float64() < rate
+	}
+}
+
+type logEntryKey struct{}
+
+// logEntry has the request-scoped logger enriched by handlers
+type logEntry struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+// GetLogger returns the request-scoped logger of Logger, which has the method, path and request ID.
+// It returns slog.Default() if the request is not served by Logger.
+func GetLogger(ctx context.Context) *slog.Logger {
+	if entry, ok := ctx.Value(logEntryKey{}).(*logEntry); ok {
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+		return entry.logger
+	}
+	return slog.Default()
+}
+
+// AddLogAttrs adds the attributes to the request log and the request-scoped logger.
+func AddLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	entry, ok := ctx.Value(logEntryKey{}).(*logEntry)
+	if !ok {
+		return
+	}
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.logger = entry.logger.With(args...)
+}
+
+func (entry *logEntry) log(r *http.Request, opts *LoggerOptions, status int, bytes int64, d time.Duration) {
+	level := slog.LevelInfo
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("pattern", michi.RoutePattern(r)),
+		slog.Int("status", status),
+		slog.Int64("bytes", bytes),
+		slog.Duration("duration", d),
+		slog.String("remote_ip", remoteIP(r)),
+	}
+	if r.URL.RawQuery != "" {
+		attrs = append(attrs, slog.String("query", redactQuery(r.URL.Query(), opts.RedactQuery)))
+	}
+	if len(opts.Headers) > 0 {
+		var headers []any
+		for _, name := range opts.Headers {
+			value := r.Header.Get(name)
+			if value == "" {
+				continue
+			}
+			if slices.ContainsFunc(opts.RedactHeaders, func(s string) bool { return strings.EqualFold(s, name) }) {
+				value = redacted
+			}
+			headers = append(headers, slog.String(name, value))
+		}
+		attrs = append(attrs, slog.Group("headers", headers...))
+	}
+	entry.mu.Lock()
+	logger := entry.logger
+	entry.mu.Unlock()
+	// logger has the attributes added by AddLogAttrs
+	logger.LogAttrs(r.Context(), level, "request", attrs...)
+}
+
+// redactQuery encodes the query with the values of names redacted.
+// The query is kept encoded, so that the values cannot forge parameters or break the log line.
+func redactQuery(query url.Values, names []string) string {
+	for _, name := range names {
+		if values, ok := query[name]; ok {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	// "[REDACTED]" has no characters separating the parameters
+	return strings.ReplaceAll(query.Encode(), url.QueryEscape(redacted), redacted)
+}
+
+// writeCLF writes the request in the Common Log Format, or the Combined Log Format
+func writeCLF(w io.Writer, format LogFormat, r *http.Request, redactNames []string, status int, bytes int64, start time.Time) {
+	user := "-"
+	if name, _, ok := r.BasicAuth(); ok && name != "" {
+		user = name
+	}
+	uri := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		uri += "?" + redactQuery(r.URL.Query(), redactNames)
+	}
+	size := "-"
+	if bytes > 0 {
+		size = fmt.Sprint(bytes)
+	}
+	line := fmt.Sprintf("%s - %s [%s] %q %d %s", remoteIP(r), user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+uri+" "+r.Proto, status, size)
+	if format == LogCombined {
+		line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+	}
+	_, _ = io.WriteString(w, line+"\n")
+}
+
+// remoteIP returns the client IP resolved by RealIP, or the IP of RemoteAddr
+func remoteIP(r *http.Request) string {
+	if info, ok := GetClientInfo(r.Context()); ok && info.IP.IsValid() {
+		return info.IP.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,156 @@
+package middleware_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r := michi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.LoggerWithOptions(middleware.LoggerOptions{
+		Logger:      slog.New(slog.NewJSONHandler(&buf, nil)),
+		Headers:     []string{"Authorization", "X-Tenant"},
+		RedactQuery: []string{"token"},
+	}))
+	r.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		middleware.AddLogAttrs(r.Context(), slog.String("user", r.PathValue("id")))
+		middleware.GetLogger(r.Context()).Info("handler")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/users/1?token=secret&page=2", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Tenant", "t")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	dec := json.NewDecoder(&buf)
+	var handlerLog, requestLog map[string]any
+	if err := dec.Decode(&handlerLog); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&requestLog); err != nil {
+		t.Fatal(err)
+	}
+	if handlerLog["request_id"] != "abc" || handlerLog["user"] != "1" {
+		t.Errorf("request-scoped logger got: %v", handlerLog)
+	}
+	want := map[string]any{
+		"level":      "INFO",
+		"msg":        "request",
+		"method":     "GET",
+		"path":       "/users/1",
+		"request_id": "abc",
+		"user":       "1",
+		"pattern":    "GET /users/{id}",
+		"status":     float64(201),
+		"bytes":      float64(5),
+		"remote_ip":  "192.0.2.1",
+		"query":      "page=2&token=[REDACTED]",
+		"headers":    map[string]any{"Authorization": "[REDACTED]", "X-Tenant": "t"},
+	}
+	for k, v := range want {
+		got, _ := json.Marshal(requestLog[k])
+		w, _ := json.Marshal(v)
+		if string(got) != string(w) {
+			t.Errorf("%s got: %s want: %s", k, got, w)
+		}
+	}
+}
+
+func TestLoggerFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format middleware.LogFormat
+		want   *regexp.Regexp
+	}{
+		{
+			name:   "common",
+			format: middleware.LogCommon,
+			want:   regexp.MustCompile(`^192\.0\.2\.1 - user \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /a\?q=1%26admin%3Dtrue%0A HTTP/1\.1" 404 19\n$`),
+		},
+		{
+			name:   "combined",
+			format: middleware.LogCombined,
+			want:   regexp.MustCompile(`^192\.0\.2\.1 - user \[.+\] "GET /a\?q=1%26admin%3Dtrue%0A HTTP/1\.1" 404 19 "https://example.com/" "test"\n$`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := middleware.LoggerWithOptions(middleware.LoggerOptions{Format: tt.format, Output: &buf})(http.NotFoundHandler())
+			// the encoded query cannot forge parameters or break the line
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/a?q=1%26admin%3Dtrue%0A", nil)
+			req.SetBasicAuth("user", "password")
+			req.Header.Set("Referer", "https://example.com/")
+			req.Header.Set("User-Agent", "test")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if !tt.want.MatchString(buf.String()) {
+				t.Errorf("log got: %q want: %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerSample(t *testing.T) {
+	var buf bytes.Buffer
+	h := middleware.LoggerWithOptions(middleware.LoggerOptions{
+		Format: middleware.LogCommon,
+		Output: &buf,
+		Sample: middleware.SampleRate(0),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://example.com/ok", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://example.com/error", nil))
+	if got := bytes.Count(buf.Bytes(), []byte("\n")); got != 1 {
+		t.Errorf("logged requests got: %v want: %v", got, 1)
+	}
+}
+
+// hijackRecorder is a httptest.ResponseRecorder implementing http.Hijacker like the writers of net/http
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+func TestLoggerStreaming(t *testing.T) {
+	var buf bytes.Buffer
+	h := middleware.LoggerWithOptions(middleware.LoggerOptions{Format: middleware.LogCommon, Output: &buf})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		rc := http.NewResponseController(w)
+		if err := rc.Flush(); err != nil {
+			t.Errorf("Flush got: %v want: nil", err)
+		}
+		if _, _, err := rc.Hijack(); err != nil {
+			t.Errorf("Hijack got: %v want: nil", err)
+		}
+	}))
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/events", nil))
+	if !w.Flushed || !w.hijacked {
+		t.Errorf("Flushed, hijacked got: %v, %v want: true, true", w.Flushed, w.hijacked)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`" 200 9`)) {
+		t.Errorf("log got: %q want: 200 9", buf.String())
+	}
+}
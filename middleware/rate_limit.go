@@ -0,0 +1,314 @@
+package middleware
+
+import (
+	"context"
+	"hash/maphash"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitOptions are the options of RateLimitWithOptions.
+type RateLimitOptions struct {
+	// Limit is the number of requests allowed in Window
+	Limit int
+	// Window is the period of Limit
+	Window time.Duration
+	// Algorithm is the rate limiting algorithm, TokenBucket if nil
+	Algorithm RateLimitAlgorithm
+	// Key returns the client of the request, KeyByIP if nil. The IP is used if it returns an empty string.
+	Key func(r *http.Request) string
+	// Store stores the states of the clients, a new MemoryStore if nil
+	Store RateLimitStore
+	// Name is the prefix of the keys in Store, so that the limits sharing Store have their own budgets.
+	// A name unique to each RateLimitWithOptions call is used if empty.
+	Name string
+	// Handler responds to the limited requests, 429 Too Many Requests as plain text if nil
+	Handler http.Handler
+}
+
+// RateLimitResult is the result of taking a request from the budget of a client.
+type RateLimitResult struct {
+	// Allowed is true if the request is within the limit
+	Allowed bool
+	// Remaining is the number of requests remaining
+	Remaining int
+	// Reset is the time until the budget is fully restored
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, it is zero if Allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitState is the state of a client stored in RateLimitStore.
+type RateLimitState struct {
+	// Time is the last refill of TokenBucket, or the start of the current window of SlidingWindow
+	Time time.Time
+	// Value is the tokens of TokenBucket, or the requests in the current window of SlidingWindow
+	Value float64
+	// Prev is the requests in the previous window of SlidingWindow
+	Prev float64
+}
+
+// RateLimitAlgorithm is a rate limiting algorithm.
+type RateLimitAlgorithm interface {
+	// Take takes a request from state, it is called atomically for each client
+	Take(state *RateLimitState, limit int, window time.Duration, now time.Time) RateLimitResult
+	// TTL is the time the state must be kept after the last request
+	TTL(window time.Duration) time.Duration
+}
+
+// RateLimitStore stores the states of clients.
+// Implementations backed by a shared database can share limits among servers.
+type RateLimitStore interface {
+	// Update calls fn with the state of key atomically, and keeps the state for ttl.
+	// The state is zero if key does not exist or is expired.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state *RateLimitState)) error
+}
+
+// rateLimitNames makes the default RateLimitOptions.Name unique
+var rateLimitNames atomic.Int64
+
+// RateLimit is a middleware that limits the requests of each client IP to limit per window with TokenBucket.
+// The limited requests are rejected with 429 Too Many Requests and Retry-After.
+// All responses have the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+//
+// Each call of RateLimit has its own budget, so routes and groups can have different limits by With:
+//
+//	r.Use(middleware.RateLimit(100, time.Minute))
+//	r.With(middleware.RateLimit(5, time.Minute)).HandleFunc("POST /login", login)
+func RateLimit(limit int, window time.Duration) func(http.Handler) http.Handler {
+	return RateLimitWithOptions(RateLimitOptions{Limit: limit, Window: window})
+}
+
+// RateLimitWithOptions returns a RateLimit middleware with the options.
+// If Store fails, the request is allowed.
+func RateLimitWithOptions(opts RateLimitOptions) func(http.Handler) http.Handler {
+	if opts.Limit < 1 || opts.Window <= 0 {
+		panic("middleware: RateLimit limit and window must be positive")
+	}
+	if opts.Algorithm == nil {
+		opts.Algorithm = TokenBucket{}
+	}
+	if opts.Key == nil {
+		opts.Key = KeyByIP
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.Name == "" {
+		opts.Name = "ratelimit" + strconv.FormatInt(rateLimitNames.Add(1), 10)
+	}
+	if opts.Handler == nil {
+		opts.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		})
+	}
+	ttl := opts.Algorithm.TTL(opts.Window)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := opts.Key(r)
+			if key == "" {
+				key = KeyByIP(r)
+			}
+			var result RateLimitResult
+			err := opts.Store.Update(r.Context(), opts.Name+":"+key, ttl, func(state *RateLimitState) {
+				result = opts.Algorithm.Take(state, opts.Limit, opts.Window, time.Now())
+			})
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(opts.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+				opts.Handler.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// KeyByIP returns the client IP of the request resolved by RealIP, or the IP of RemoteAddr.
+func KeyByIP(r *http.Request) string {
+	return remoteIP(r)
+}
+
+// KeyByHeader returns a RateLimitOptions.Key using the header such as an API key.
+func KeyByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByQuery returns a RateLimitOptions.Key using the query parameter.
+func KeyByQuery(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+// TokenBucket is a bucket of limit tokens refilled at the rate of limit per window.
+// It allows bursts up to limit requests.
+type TokenBucket struct{}
+
+// Take takes a token if the bucket has one
+func (TokenBucket) Take(state *RateLimitState, limit int, window time.Duration, now time.Time) RateLimitResult {
+	capacity := float64(limit)
+	perToken := window / time.Duration(limit)
+	if state.Time.IsZero() {
+		state.Value = capacity
+	} else {
+		state.Value = math.Min(capacity, state.Value+float64(now.Sub(state.Time))/float64(perToken))
+	}
+	state.Time = now
+	result := RateLimitResult{Allowed: state.Value >= 1}
+	if result.Allowed {
+		state.Value--
+	} else {
+		result.RetryAfter = time.Duration((1 - state.Value) * float64(perToken))
+	}
+	result.Remaining = int(state.Value)
+	result.Reset = time.Duration((capacity - state.Value) * float64(perToken))
+	return result
+}
+
+// TTL returns window, the time to refill the bucket fully
+func (TokenBucket) TTL(window time.Duration) time.Duration {
+	return window
+}
+
+// SlidingWindow counts the requests in the sliding window, estimated by the counts of the current and the previous fixed windows.
+// Unlike TokenBucket, it does not allow bursts over limit in any window.
+type SlidingWindow struct{}
+
+// Take counts the request if the estimated count of the sliding window is less than limit
+func (SlidingWindow) Take(state *RateLimitState, limit int, window time.Duration, now time.Time) RateLimitResult {
+	start := now.Truncate(window)
+	if !state.Time.Equal(start) {
+		if state.Time.Equal(start.Add(-window)) {
+			state.Prev = state.Value
+		} else {
+			state.Prev = 0
+		}
+		state.Value = 0
+		state.Time = start
+	}
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+	count := state.Prev*weight + state.Value
+	result := RateLimitResult{Allowed: count+1 <= float64(limit)}
+	if result.Allowed {
+		state.Value++
+		count++
+	} else {
+		result.RetryAfter = slidingRetryAfter(state, float64(limit), window, elapsed)
+	}
+	result.Remaining = max(0, int(float64(limit)-count))
+	// the previous window slides out at the end of the current window, and the current window at the end of the next
+	result.Reset = window - elapsed
+	if state.Value > 0 {
+		result.Reset += window
+	}
+	return result
+}
+
+// slidingRetryAfter returns the time until the estimated count becomes limit-1
+func slidingRetryAfter(state *RateLimitState, limit float64, window time.Duration, elapsed time.Duration) time.Duration {
+	if state.Value <= limit-1 {
+		// prev*(1-x/window) + value <= limit-1
+		x := float64(window) * (1 - (limit-1-state.Value)/state.Prev)
+		return time.Duration(x) - elapsed
+	}
+	// in the next window, value*(1-x/window) <= limit-1
+	x := float64(window) * (1 - (limit-1)/state.Value)
+	return window - elapsed + time.Duration(x)
+}
+
+// TTL returns two windows, the current and the previous windows
+func (SlidingWindow) TTL(window time.Duration) time.Duration {
+	return 2 * window
+}
+
+// memoryStoreShards is the number of shards of MemoryStore, which reduces lock contention
+const memoryStoreShards = 64
+
+// MemoryStore is an in-memory RateLimitStore sharded by keys.
+// Expired states are evicted while updating.
+type MemoryStore struct {
+	seed   maphash.Seed
+	shards [memoryStoreShards]memoryShard
+}
+
+type memoryShard struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	// updates counts the updates until the next eviction
+	updates int
+}
+
+type memoryEntry struct {
+	state   RateLimitState
+	expires time.Time
+}
+
+// NewMemoryStore creates a MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i].entries = map[string]*memoryEntry{}
+	}
+	return s
+}
+
+// Update calls fn with the state of key
+func (s *MemoryStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state *RateLimitState)) error {
+	shard := &s.shards[maphash.String(s.seed, key)%memoryStoreShards]
+	now := time.Now()
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.evict(now)
+	e, ok := shard.entries[key]
+	if !ok || now.After(e.expires) {
+		e = &memoryEntry{}
+		shard.entries[key] = e
+	}
+	fn(&e.state)
+	e.expires = now.Add(ttl)
+	return nil
+}
+
+// Len returns the number of keys, including the expired keys not evicted yet
+func (s *MemoryStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		n += len(s.shards[i].entries)
+		s.shards[i].mu.Unlock()
+	}
+	return n
+}
+
+// evict removes the expired entries once the updates reach the number of entries, so that it costs O(1) amortized
+func (shard *memoryShard) evict(now time.Time) {
+	shard.updates++
+	if shard.updates < len(shard.entries) {
+		return
+	}
+	shard.updates = 0
+	for key, e := range shard.entries {
+		if now.After(e.expires) {
+			delete(shard.entries, key)
+		}
+	}
+}
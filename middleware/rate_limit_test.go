@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestRateLimit(t *testing.T) {
+	r := michi.NewRouter()
+	r.Use(middleware.RateLimit(3, time.Minute))
+	r.HandleFunc("GET /a", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(middleware.RateLimitWithOptions(middleware.RateLimitOptions{
+		Limit:     1,
+		Window:    time.Minute,
+		Algorithm: middleware.SlidingWindow{},
+		Key:       middleware.KeyByHeader("X-Api-Key"),
+	})).HandleFunc("GET /b", func(w http.ResponseWriter, r *http.Request) {})
+
+	type response struct {
+		code       int
+		remaining  string
+		retryAfter string
+	}
+	serve := func(path, ip, apiKey string) response {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("RateLimit-Limit"); got == "" {
+			t.Errorf("RateLimit-Limit must be set")
+		}
+		return response{code: w.Code, remaining: w.Header().Get("RateLimit-Remaining"), retryAfter: w.Header().Get("Retry-After")}
+	}
+	tests := []struct {
+		name   string
+		path   string
+		ip     string
+		apiKey string
+		want   response
+	}{
+		{name: "1st", path: "/a", ip: "192.0.2.1", want: response{code: http.StatusOK, remaining: "2"}},
+		{name: "2nd", path: "/a", ip: "192.0.2.1", want: response{code: http.StatusOK, remaining: "1"}},
+		{name: "3rd", path: "/a", ip: "192.0.2.1", want: response{code: http.StatusOK, remaining: "0"}},
+		{name: "4th is limited", path: "/a", ip: "192.0.2.1", want: response{code: http.StatusTooManyRequests, remaining: "0", retryAfter: "20"}},
+		{name: "another client", path: "/a", ip: "192.0.2.2", want: response{code: http.StatusOK, remaining: "2"}},
+		// /b consumes the budget of Use and With
+		{name: "route limit by api key", path: "/b", ip: "192.0.2.3", apiKey: "k1", want: response{code: http.StatusOK, remaining: "0"}},
+		{name: "route limit is limited", path: "/b", ip: "192.0.2.3", apiKey: "k1", want: response{code: http.StatusTooManyRequests, remaining: "0", retryAfter: "*"}},
+		{name: "route limit of another key", path: "/b", ip: "192.0.2.3", apiKey: "k2", want: response{code: http.StatusOK, remaining: "0"}},
+	}
+	for _, tt := range tests {
+		got := serve(tt.path, tt.ip, tt.apiKey)
+		// Retry-After of the sliding window depends on the current time
+		if tt.want.retryAfter == "*" && got.retryAfter != "" {
+			got.retryAfter = "*"
+		}
+		if got != tt.want {
+			t.Errorf("%s got: %+v want: %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	var state middleware.RateLimitState
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		elapsed time.Duration
+		want    middleware.RateLimitResult
+	}{
+		{elapsed: 0, want: middleware.RateLimitResult{Allowed: true, Remaining: 1, Reset: 5 * time.Second}},
+		{elapsed: 0, want: middleware.RateLimitResult{Allowed: true, Remaining: 0, Reset: 10 * time.Second}},
+		{elapsed: time.Second, want: middleware.RateLimitResult{Allowed: false, Remaining: 0, Reset: 9 * time.Second, RetryAfter: 4 * time.Second}},
+		{elapsed: 4 * time.Second, want: middleware.RateLimitResult{Allowed: true, Remaining: 0, Reset: 10 * time.Second}},
+		{elapsed: time.Minute, want: middleware.RateLimitResult{Allowed: true, Remaining: 1, Reset: 5 * time.Second}},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.elapsed)
+		if got := (middleware.TokenBucket{}).Take(&state, 2, 10*time.Second, now); got != tt.want {
+			t.Errorf("Take %d got: %+v want: %+v", i, got, tt.want)
+		}
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	var state middleware.RateLimitState
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at   time.Duration
+		want middleware.RateLimitResult
+	}{
+		{at: 0, want: middleware.RateLimitResult{Allowed: true, Remaining: 1, Reset: 20 * time.Second}},
+		{at: 5 * time.Second, want: middleware.RateLimitResult{Allowed: true, Remaining: 0, Reset: 15 * time.Second}},
+		{at: 6 * time.Second, want: middleware.RateLimitResult{Allowed: false, Remaining: 0, Reset: 14 * time.Second, RetryAfter: 9 * time.Second}},
+		// the previous window has 2 requests, the weight is 0.5 at 15s
+		{at: 15 * time.Second, want: middleware.RateLimitResult{Allowed: true, Remaining: 0, Reset: 15 * time.Second}},
+		{at: 16 * time.Second, want: middleware.RateLimitResult{Allowed: false, Remaining: 0, Reset: 14 * time.Second, RetryAfter: 4 * time.Second}},
+		{at: 40 * time.Second, want: middleware.RateLimitResult{Allowed: true, Remaining: 1, Reset: 20 * time.Second}},
+	}
+	for _, tt := range tests {
+		if got := (middleware.SlidingWindow{}).Take(&state, 2, 10*time.Second, start.Add(tt.at)); got != tt.want {
+			t.Errorf("Take at %v got: %+v want: %+v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	s := middleware.NewMemoryStore()
+	for i := range 10000 {
+		err := s.Update(context.Background(), strconv.Itoa(i), time.Nanosecond, func(state *middleware.RateLimitState) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := s.Len(); got >= 10000/2 {
+		t.Errorf("Len got: %v want: expired keys evicted", got)
+	}
+}
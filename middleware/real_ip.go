@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientInfo is the client of the request resolved by RealIP.
+type ClientInfo struct {
+	// IP is the IP of the client
+	IP netip.Addr
+	// Scheme is the scheme requested by the client, "http" or "https"
+	Scheme string
+	// Host is the host requested by the client
+	Host string
+}
+
+type clientInfoKey struct{}
+
+// GetClientInfo returns the client resolved by RealIP.
+func GetClientInfo(ctx context.Context) (ClientInfo, bool) {
+	info, ok := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info, ok
+}
+
+// RealIP is a middleware that resolves the client IP, scheme and host from the Forwarded (RFC 7239),
+// X-Forwarded-For, X-Forwarded-Proto, X-Forwarded-Host and X-Real-IP headers, and stores them in the context.
+// Get them by GetClientInfo, they are also used by Logger and KeyByIP. RemoteAddr is not modified.
+//
+// The headers are honoured only if the peer is in trustedProxies, which are CIDRs or IPs.
+// The chain of proxies is walked from right to left, and the first hop not in trustedProxies is the client,
+// so that the hops added by the client itself are ignored.
+// It panics if trustedProxies has an invalid CIDR.
+func RealIP(trustedProxies ...string) func(http.Handler) http.Handler {
+	var trusted []netip.Prefix
+	for _, s := range trustedProxies {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(s)
+			if addrErr != nil {
+				panic("middleware: invalid trusted proxy: " + err.Error())
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trusted = append(trusted, prefix.Masked())
+	}
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := ClientInfo{Scheme: "http", Host: r.Host}
+			if r.TLS != nil {
+				info.Scheme = "https"
+			}
+			if peer, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+				info.IP = peer.Addr().Unmap()
+				if isTrusted(info.IP) {
+					resolveForwarded(r, &info, isTrusted)
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientInfoKey{}, info)))
+		})
+	}
+}
+
+// forwardedHop is a hop of the proxy chain
+type forwardedHop struct {
+	// ip is invalid if the node is obfuscated, unknown or malformed
+	ip     netip.Addr
+	proto  string
+	host   string
+	parsed bool
+}
+
+// resolveForwarded walks the hops from right to left, and sets the first untrusted hop to info
+func resolveForwarded(r *http.Request, info *ClientInfo, isTrusted func(netip.Addr) bool) {
+	var hops []forwardedHop
+	switch {
+	case r.Header.Get("Forwarded") != "":
+		hops = parseForwarded(strings.Join(r.Header.Values("Forwarded"), ","))
+	case r.Header.Get("X-Forwarded-For") != "":
+		hops = parseXForwarded(r, info)
+	case r.Header.Get("X-Real-Ip") != "":
+		addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-Ip")))
+		hops = []forwardedHop{{ip: addr.Unmap(), parsed: err == nil}}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if !hop.parsed {
+			// the hops on the left of a malformed hop can not be trusted
+			return
+		}
+		info.IP = hop.ip
+		if hop.proto == "http" || hop.proto == "https" {
+			info.Scheme = hop.proto
+		}
+		if hop.host != "" {
+			info.Host = hop.host
+		}
+		if !isTrusted(hop.ip) {
+			return
+		}
+	}
+}
+
+// parseForwarded parses the Forwarded header, each element is a hop
+func parseForwarded(header string) []forwardedHop {
+	var hops []forwardedHop
+	for _, element := range strings.Split(header, ",") {
+		var hop forwardedHop
+		for _, pair := range strings.Split(element, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			value = strings.Trim(value, `"`)
+			switch strings.ToLower(key) {
+			case "for":
+				hop.ip, hop.parsed = parseNode(value)
+			case "proto":
+				hop.proto = strings.ToLower(value)
+			case "host":
+				hop.host = value
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseNode parses the node of the Forwarded header such as "192.0.2.1", "192.0.2.1:80" or "[2001:db8::1]:80"
+func parseNode(node string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(node, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// parseXForwarded parses X-Forwarded-For with X-Forwarded-Proto and X-Forwarded-Host.
+// If the numbers of the values differ from X-Forwarded-For, the last values are set to info
+func parseXForwarded(r *http.Request, info *ClientInfo) []forwardedHop {
+	ips := splitValues(r.Header.Values("X-Forwarded-For"))
+	hops := make([]forwardedHop, len(ips))
+	for i, ip := range ips {
+		hops[i].ip, hops[i].parsed = parseNode(ip)
+	}
+	if protos := splitValues(r.Header.Values("X-Forwarded-Proto")); len(protos) == len(hops) {
+		for i, proto := range protos {
+			hops[i].proto = strings.ToLower(proto)
+		}
+	} else if len(protos) > 0 {
+		if proto := strings.ToLower(protos[len(protos)-1]); proto == "http" || proto == "https" {
+			info.Scheme = proto
+		}
+	}
+	if hosts := splitValues(r.Header.Values("X-Forwarded-Host")); len(hosts) == len(hops) {
+		for i, host := range hosts {
+			hops[i].host = host
+		}
+	} else if len(hosts) > 0 {
+		info.Host = hosts[len(hosts)-1]
+	}
+	return hops
+}
+
+// splitValues splits comma separated header values
+func splitValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			result = append(result, strings.TrimSpace(v))
+		}
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestRealIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		header     map[string]string
+		ip         string
+		want       middleware.ClientInfo
+	}{
+		{
+			name:       "untrusted peer",
+			ip:         "198.51.100.1",
+			remoteAddr: "198.51.100.1:1234",
+			header:     map[string]string{"X-Forwarded-For": "203.0.113.1", "X-Forwarded-Proto": "https"},
+			want:       middleware.ClientInfo{Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "X-Forwarded-For spoofed by the client",
+			ip:         "203.0.113.1",
+			remoteAddr: "10.0.0.1:1234",
+			header:     map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.1, 10.0.0.2", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			want:       middleware.ClientInfo{Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "X-Forwarded-Proto of each hop",
+			ip:         "203.0.113.1",
+			remoteAddr: "10.0.0.1:1234",
+			tls:        true,
+			header:     map[string]string{"X-Forwarded-For": "203.0.113.1, 10.0.0.2", "X-Forwarded-Proto": "http, https"},
+			want:       middleware.ClientInfo{Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "all hops are trusted",
+			ip:         "10.0.0.3",
+			remoteAddr: "10.0.0.1:1234",
+			header:     map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			want:       middleware.ClientInfo{Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "malformed hop",
+			ip:         "10.0.0.2",
+			remoteAddr: "10.0.0.1:1234",
+			header:     map[string]string{"X-Forwarded-For": "203.0.113.1, unknown, 10.0.0.2"},
+			want:       middleware.ClientInfo{Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "Forwarded",
+			ip:         "2001:db8::1",
+			remoteAddr: "[::ffff:10.0.0.1]:1234",
+			header: map[string]string{
+				"Forwarded":       `for="[2001:db8::1]:4711";proto=https;host=api.example.com, for=10.0.0.2`,
+				"X-Forwarded-For": "198.51.100.1",
+			},
+			want: middleware.ClientInfo{Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "X-Real-IP",
+			ip:         "203.0.113.1",
+			remoteAddr: "10.0.0.1:1234",
+			header:     map[string]string{"X-Real-IP": "203.0.113.1"},
+			want:       middleware.ClientInfo{Scheme: "http", Host: "example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got middleware.ClientInfo
+			var ok bool
+			h := middleware.RealIP("10.0.0.0/8", "192.0.2.1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, ok = middleware.GetClientInfo(r.Context())
+				if r.RemoteAddr != tt.remoteAddr {
+					t.Errorf("RemoteAddr got: %v want: %v", r.RemoteAddr, tt.remoteAddr)
+				}
+			}))
+			r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if !ok {
+				t.Fatal("GetClientInfo must report true")
+			}
+			if got.IP.String() != tt.ip {
+				t.Errorf("IP got: %v want: %v", got.IP, tt.ip)
+			}
+			if got.Scheme != tt.want.Scheme || got.Host != tt.want.Host {
+				t.Errorf("ClientInfo got: %+v want: %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-michi/michi"
+)
+
+// Panic is a panic recovered by Recoverer.
+type Panic struct {
+	// Value is the value passed to panic
+	Value any
+	// Stack is the stack trace from the panicking function, without the frames of runtime and net/http
+	Stack []byte
+	// Pattern is the pattern of the route which panicked, see michi.RoutePattern
+	Pattern string
+	// RequestID is the request ID stored by RequestID
+	RequestID string
+}
+
+// PanicReporter reports panics to an error tracker.
+type PanicReporter interface {
+	ReportPanic(r *http.Request, p *Panic)
+}
+
+// PanicReporterFunc is a function implementing PanicReporter.
+type PanicReporterFunc func(r *http.Request, p *Panic)
+
+// ReportPanic calls f(r, p)
+func (f PanicReporterFunc) ReportPanic(r *http.Request, p *Panic) {
+	f(r, p)
+}
+
+// RecovererOptions are the options of RecovererWithOptions.
+type RecovererOptions struct {
+	// Logger logs panics, the request-scoped logger of Logger or slog.Default() if nil
+	Logger *slog.Logger
+	// Handler responds to the panicked request if the response is not started, PanicText if nil
+	Handler http.Handler
+	// Reporters are called with every recovered panic
+	Reporters []PanicReporter
+}
+
+// Recoverer is a middleware that recovers from panics, logs them with the trimmed stack trace,
+// the matched route and the request ID, and responds 500 Internal Server Error if the response is not started.
+// http.ErrAbortHandler is panicked again to abort the response.
+func Recoverer(next http.Handler) http.Handler {
+	return RecovererWithOptions(RecovererOptions{})(next)
+}
+
+// RecovererWithOptions returns a Recoverer middleware with the options.
+func RecovererWithOptions(opts RecovererOptions) func(http.Handler) http.Handler {
+	if opts.Handler == nil {
+		opts.Handler = PanicText
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := NewWrapResponseWriter(w)
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				p := &Panic{
+					Value:     v,
+					Stack:     trimStack(debug.Stack()),
+					Pattern:   michi.RoutePattern(r),
+					RequestID: GetRequestID(r.Context()),
+				}
+				logger := opts.Logger
+				if logger == nil {
+					logger = GetLogger(r.Context())
+				}
+				logger.ErrorContext(r.Context(), "panic recovered",
+					slog.String("panic", fmt.Sprint(v)),
+					slog.String("pattern", p.Pattern),
+					slog.String("request_id", p.RequestID),
+					slog.String("stack", string(p.Stack)),
+				)
+				for _, reporter := range opts.Reporters {
+					reporter.ReportPanic(r, p)
+				}
+				// the status code and the headers can not be changed after the response is started
+				if ww.Status() == 0 {
+					opts.Handler.ServeHTTP(w, r)
+				}
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// trimStack removes the frames before the panic, which are of debug.Stack, Recoverer and runtime,
+// and the frames of net/http serving the connection
+func trimStack(stack []byte) []byte {
+	lines := bytes.Split(bytes.TrimSpace(stack), []byte("\n"))
+	// lines[0] is "goroutine N [running]:", each frame has the function line and the file line
+	start, end := 1, len(lines)
+	for i := 1; i < len(lines); i += 2 {
+		fn := lines[i]
+		if bytes.HasPrefix(fn, []byte("panic(")) {
+			start = i + 2
+		}
+		if bytes.HasPrefix(fn, []byte("net/http.serverHandler.ServeHTTP(")) {
+			end = i
+			break
+		}
+	}
+	if start >= end {
+		return stack
+	}
+	trimmed := append([][]byte{lines[0]}, lines[start:end]...)
+	return append(bytes.Join(trimmed, []byte("\n")), '\n')
+}
+
+// PanicText responds 500 Internal Server Error as plain text.
+var PanicText http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+})
+
+// PanicJSON responds 500 Internal Server Error as an RFC 9457 problem detail.
+var PanicJSON http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = io.WriteString(w, `{"title":"Internal Server Error","status":500}`+"\n")
+})
+
+// PanicHTML responds 500 Internal Server Error as an HTML page.
+var PanicHTML http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = io.WriteString(w, "<!DOCTYPE html>\n<html><head><title>500 Internal Server Error</title></head>"+
+		"<body><h1>500 Internal Server Error</h1></body></html>\n")
+})
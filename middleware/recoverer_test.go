@@ -0,0 +1,102 @@
+package middleware_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestRecoverer(t *testing.T) {
+	var buf bytes.Buffer
+	var reported *middleware.Panic
+	r := michi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RecovererWithOptions(middleware.RecovererOptions{
+		Logger:  slog.New(slog.NewTextHandler(&buf, nil)),
+		Handler: middleware.PanicJSON,
+		Reporters: []middleware.PanicReporter{middleware.PanicReporterFunc(func(r *http.Request, p *middleware.Panic) {
+			reported = p
+		})},
+	}))
+	r.HandleFunc("GET /panic/{id}", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	r.HandleFunc("GET /started", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	})
+	r.HandleFunc("GET /abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	t.Run("respond", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/panic/1", nil)
+		req.Header.Set("X-Request-Id", "abc")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("StatusCode got: %v want: %v", w.Code, http.StatusInternalServerError)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Errorf("Content-Type got: %v want: %v", got, "application/problem+json")
+		}
+		if reported == nil || reported.Value != "boom" || reported.Pattern != "GET /panic/{id}" || reported.RequestID != "abc" {
+			t.Fatalf("reported got: %+v", reported)
+		}
+		if !strings.HasPrefix(string(reported.Stack), "goroutine ") || !strings.Contains(string(reported.Stack), "TestRecoverer") {
+			t.Errorf("Stack got: %s", reported.Stack)
+		}
+		if strings.Contains(string(reported.Stack), "runtime/debug.Stack") {
+			t.Errorf("Stack must be trimmed, got: %s", reported.Stack)
+		}
+		for _, want := range []string{"panic=boom", `pattern="GET /panic/{id}"`, "request_id=abc"} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("log got: %v want: %v", buf.String(), want)
+			}
+		}
+	})
+	t.Run("response started", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/started", nil))
+		if w.Code != http.StatusAccepted {
+			t.Errorf("StatusCode got: %v want: %v", w.Code, http.StatusAccepted)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("Body got: %q want: empty", w.Body.String())
+		}
+	})
+	t.Run("abort", func(t *testing.T) {
+		defer func() {
+			if v := recover(); !errors.Is(v.(error), http.ErrAbortHandler) {
+				t.Errorf("recover got: %v want: %v", v, http.ErrAbortHandler)
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://example.com/abort", nil))
+		t.Error("http.ErrAbortHandler must be panicked again")
+	})
+}
+
+func TestRecovererStreaming(t *testing.T) {
+	h := middleware.Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		rc := http.NewResponseController(w)
+		if err := rc.Flush(); err != nil {
+			t.Errorf("Flush got: %v want: nil", err)
+		}
+		if _, _, err := rc.Hijack(); err != nil {
+			t.Errorf("Hijack got: %v want: nil", err)
+		}
+	}))
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/events", nil))
+	if !w.Flushed || !w.hijacked {
+		t.Errorf("Flushed, hijacked got: %v, %v want: true, true", w.Flushed, w.hijacked)
+	}
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the default header of the request ID
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// RequestIDOptions are the options of RequestIDWithOptions.
+type RequestIDOptions struct {
+	// Header is the header of the request ID, RequestIDHeader if empty
+	Header string
+	// Generate generates a new request ID, NewUUIDv7 if nil
+	Generate func() string
+	// Validate reports whether the incoming request ID is accepted, ValidRequestID if nil.
+	// Invalid request IDs are replaced with a new one.
+	Validate func(id string) bool
+}
+
+// RequestID is a middleware that accepts the request ID of the X-Request-Id header or generates a UUIDv7,
+// stores it in the request context, and sets it to the response header.
+func RequestID(next http.Handler) http.Handler {
+	return RequestIDWithOptions(RequestIDOptions{})(next)
+}
+
+// RequestIDWithOptions returns a RequestID middleware with the options.
+func RequestIDWithOptions(opts RequestIDOptions) func(http.Handler) http.Handler {
+	if opts.Header == "" {
+		opts.Header = RequestIDHeader
+	}
+	if opts.Generate == nil {
+		opts.Generate = NewUUIDv7
+	}
+	if opts.Validate == nil {
+		opts.Validate = ValidRequestID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(opts.Header)
+			if id == "" || !opts.Validate(id) {
+				id = opts.Generate()
+			}
+			w.Header().Set(opts.Header, id)
+			next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// WithRequestID returns a copy of ctx with the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// GetRequestID returns the request ID stored by RequestID, or an empty string.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ValidRequestID reports whether id has 1 to 128 characters of letters, digits and "-_.:/+=".
+// It prevents header and log injection with incoming request IDs.
+func ValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':' || c == '/' || c == '+' || c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewUUIDv7 generates a UUID version 7 defined by RFC 9562, which is ordered by the generated time.
+func NewUUIDv7() string {
+	var b [16]byte
+	randomBytes(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// crockford is the Crockford's Base32 alphabet of ULID
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID, which is ordered by the generated time.
+func NewULID() string {
+	// 48 bits timestamp and 80 bits randomness
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	randomBytes(b[6:])
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	// 26 characters of 5 bits from the most significant bits, the first character has only 3 bits
+	var s [26]byte
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+func randomBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic("middleware: crypto/rand failed: " + err.Error())
+	}
+}
+
+// RequestIDTransport is a http.RoundTripper that forwards the request ID of the request context
+// to outgoing requests, so that the logs of services can be correlated.
+//
+//	client := &http.Client{Transport: &middleware.RequestIDTransport{}}
+//	req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+//	client.Do(req)
+type RequestIDTransport struct {
+	// Base is the underlying http.RoundTripper, http.DefaultTransport if nil
+	Base http.RoundTripper
+	// Header is the header of the request ID, RequestIDHeader if empty
+	Header string
+}
+
+// RoundTrip sets the request ID to the header if the request does not have it.
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	header := t.Header
+	if header == "" {
+		header = RequestIDHeader
+	}
+	id := GetRequestID(req.Context())
+	if id == "" || req.Header.Get(header) != "" {
+		return base.RoundTrip(req)
+	}
+	// RoundTrip must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set(header, id)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,97 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestRequestID(t *testing.T) {
+	uuidv7 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	tests := []struct {
+		name     string
+		incoming string
+		want     string
+	}{
+		{name: "accept incoming", incoming: "abc-123", want: "abc-123"},
+		{name: "generate if absent", incoming: "", want: ""},
+		{name: "replace invalid", incoming: "a b\r\nc", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = middleware.GetRequestID(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			if tt.incoming != "" {
+				r.Header.Set("X-Request-Id", tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("GetRequestID got: %v want: %v", got, tt.want)
+			}
+			if tt.want == "" && !uuidv7.MatchString(got) {
+				t.Errorf("GetRequestID got: %v want: UUIDv7", got)
+			}
+			if echo := w.Header().Get("X-Request-Id"); echo != got {
+				t.Errorf("X-Request-Id got: %v want: %v", echo, got)
+			}
+		})
+	}
+}
+
+func TestRequestIDWithOptions(t *testing.T) {
+	var got string
+	h := middleware.RequestIDWithOptions(middleware.RequestIDOptions{
+		Header:   "X-Trace-Id",
+		Generate: middleware.NewULID,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetRequestID(r.Context())
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	if !regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).MatchString(got) {
+		t.Errorf("GetRequestID got: %v want: ULID", got)
+	}
+	if echo := w.Header().Get("X-Trace-Id"); echo != got {
+		t.Errorf("X-Trace-Id got: %v want: %v", echo, got)
+	}
+}
+
+func TestNewULIDOrder(t *testing.T) {
+	a := middleware.NewULID()
+	b := middleware.NewULID()
+	if a[:10] > b[:10] {
+		t.Errorf("ULID timestamp must be ordered, got: %v then %v", a, b)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var got string
+	tr := &middleware.RequestIDTransport{Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.Header.Get("X-Request-Id")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req = req.WithContext(middleware.WithRequestID(req.Context(), "abc"))
+	if _, err := tr.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if got != "abc" {
+		t.Errorf("X-Request-Id got: %v want: %v", got, "abc")
+	}
+	if req.Header.Get("X-Request-Id") != "" {
+		t.Error("RoundTrip must not modify the request")
+	}
+}
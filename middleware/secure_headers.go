@@ -0,0 +1,285 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecureHeadersOptions are the options of SecureHeadersWithOptions. Empty fields are not sent.
+type SecureHeadersOptions struct {
+	// HSTSMaxAge is the max-age of Strict-Transport-Security
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubDomains adds includeSubDomains to Strict-Transport-Security
+	HSTSIncludeSubDomains bool
+	// HSTSPreload adds preload to Strict-Transport-Security
+	HSTSPreload bool
+	// ReferrerPolicy is the Referrer-Policy such as "strict-origin-when-cross-origin"
+	ReferrerPolicy string
+	// PermissionsPolicy is the Permissions-Policy such as "camera=(), microphone=()"
+	PermissionsPolicy string
+	// CrossOriginOpenerPolicy is the Cross-Origin-Opener-Policy such as "same-origin"
+	CrossOriginOpenerPolicy string
+	// CrossOriginEmbedderPolicy is the Cross-Origin-Embedder-Policy such as "require-corp"
+	CrossOriginEmbedderPolicy string
+	// CSP is the Content-Security-Policy
+	CSP *CSP
+	// CSPReportOnly sends the CSP as Content-Security-Policy-Report-Only
+	CSPReportOnly bool
+}
+
+// DefaultSecureHeadersOptions returns the options of SecureHeaders.
+// The CSP is created for each call, so that it can be modified without affecting the others.
+//
+//	opts := middleware.DefaultSecureHeadersOptions()
+//	opts.CSP.ImgSrc(middleware.CSPSelf, "https://cdn.example.com")
+func DefaultSecureHeadersOptions() SecureHeadersOptions {
+	return SecureHeadersOptions{
+		HSTSMaxAge:              365 * 24 * time.Hour,
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		CrossOriginOpenerPolicy: "same-origin",
+		CSP: NewCSP().
+			DefaultSrc(CSPSelf).
+			ObjectSrc(CSPNone).
+			BaseURI(CSPSelf).
+			FrameAncestors(CSPSelf),
+	}
+}
+
+// SecureHeaders is a middleware setting X-Content-Type-Options: nosniff and the headers of DefaultSecureHeadersOptions.
+func SecureHeaders(next http.Handler) http.Handler {
+	return SecureHeadersWithOptions(DefaultSecureHeadersOptions())(next)
+}
+
+// SecureHeadersWithOptions returns a SecureHeaders middleware with the options.
+//
+// If it is nested in another SecureHeaders by With, the headers of the outer middleware are replaced,
+// and the nonce of the request is kept, so that some routes can have a relaxed CSP:
+//
+//	r.Use(middleware.SecureHeadersWithOptions(opts))
+//	docs := opts
+//	docs.CSP = opts.CSP.Clone().ScriptSrc(middleware.CSPSelf, middleware.CSPUnsafeInline)
+//	r.With(middleware.SecureHeadersWithOptions(docs)).Handle("GET /docs/", docsHandler)
+func SecureHeadersWithOptions(opts SecureHeadersOptions) func(http.Handler) http.Handler {
+	headers := http.Header{}
+	headers.Set("X-Content-Type-Options", "nosniff")
+	if opts.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge/time.Second), 10)
+		if opts.HSTSIncludeSubDomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTSPreload {
+			hsts += "; preload"
+		}
+		headers.Set("Strict-Transport-Security", hsts)
+	}
+	for name, value := range map[string]string{
+		"Referrer-Policy":              opts.ReferrerPolicy,
+		"Permissions-Policy":           opts.PermissionsPolicy,
+		"Cross-Origin-Opener-Policy":   opts.CrossOriginOpenerPolicy,
+		"Cross-Origin-Embedder-Policy": opts.CrossOriginEmbedderPolicy,
+	} {
+		if value != "" {
+			headers.Set(name, value)
+		}
+	}
+	var csp string
+	cspHeader := "Content-Security-Policy"
+	if opts.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	if opts.CSP != nil {
+		csp = opts.CSP.String()
+	}
+	usesNonce := strings.Contains(csp, string(CSPNonce))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			st, nested := r.Context().Value(secureHeadersKey{}).(*secureHeadersState)
+			if nested {
+				// replace the headers of the outer middleware
+				for _, name := range st.headers {
+					h.Del(name)
+				}
+			} else {
+				st = &secureHeadersState{}
+				r = r.WithContext(context.WithValue(r.Context(), secureHeadersKey{}, st))
+			}
+			st.headers = st.headers[:0]
+			for name, values := range headers {
+				h[name] = slices.Clone(values)
+				st.headers = append(st.headers, name)
+			}
+			if csp != "" {
+				policy := csp
+				if usesNonce {
+					if st.nonce == "" {
+						b := make([]byte, 16)
+						randomBytes(b)
+						st.nonce = base64.StdEncoding.EncodeToString(b)
+					}
+					policy = strings.ReplaceAll(policy, string(CSPNonce), "'nonce-"+st.nonce+"'")
+				}
+				h.Set(cspHeader, policy)
+				st.headers = append(st.headers, cspHeader)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type secureHeadersKey struct{}
+
+// secureHeadersState is shared by the nested SecureHeaders of a request
+type secureHeadersState struct {
+	// nonce is the CSP nonce of the request
+	nonce string
+	// headers are the names of the headers set by the innermost SecureHeaders
+	headers []string
+}
+
+// GetCSPNonce returns the nonce of the request for the CSPNonce source,
+// or an empty string if the CSP does not use CSPNonce.
+//
+//	<script nonce="{{ .Nonce }}">...</script>
+func GetCSPNonce(ctx context.Context) string {
+	if st, ok := ctx.Value(secureHeadersKey{}).(*secureHeadersState); ok {
+		return st.nonce
+	}
+	return ""
+}
+
+// CSPSource is a source of a CSP directive, such as CSPSelf or a host "https://cdn.example.com".
+type CSPSource string
+
+// CSP sources.
+const (
+	CSPSelf           CSPSource = "'self'"
+	CSPNone           CSPSource = "'none'"
+	CSPUnsafeInline   CSPSource = "'unsafe-inline'"
+	CSPUnsafeEval     CSPSource = "'unsafe-eval'"
+	CSPStrictDynamic  CSPSource = "'strict-dynamic'"
+	CSPReportSample   CSPSource = "'report-sample'"
+	CSPWasmUnsafeEval CSPSource = "'wasm-unsafe-eval'"
+	CSPData           CSPSource = "data:"
+	CSPBlob           CSPSource = "blob:"
+	CSPHTTPS          CSPSource = "https:"
+	// CSPNonce is replaced by the nonce of each request, which is available by GetCSPNonce
+	CSPNonce CSPSource = "'nonce'"
+)
+
+// CSP is a builder of Content-Security-Policy. Directives are rendered in the order they are set,
+// and setting a directive again replaces its sources.
+//
+//	csp := middleware.NewCSP().
+//		DefaultSrc(middleware.CSPSelf).
+//		ScriptSrc(middleware.CSPSelf, middleware.CSPNonce).
+//		ImgSrc(middleware.CSPSelf, middleware.CSPData, "https://cdn.example.com")
+type CSP struct {
+	directives []cspDirective
+}
+
+type cspDirective struct {
+	name    string
+	sources []CSPSource
+}
+
+// NewCSP returns an empty CSP.
+func NewCSP() *CSP {
+	return &CSP{}
+}
+
+// Clone returns a copy of c to be modified without changing c.
+func (c *CSP) Clone() *CSP {
+	cp := &CSP{directives: make([]cspDirective, len(c.directives))}
+	for i, d := range c.directives {
+		cp.directives[i] = cspDirective{name: d.name, sources: slices.Clone(d.sources)}
+	}
+	return cp
+}
+
+// Directive sets the directive of name with the sources, it is a directive without value if sources are empty.
+func (c *CSP) Directive(name string, sources ...CSPSource) *CSP {
+	for i := range c.directives {
+		if c.directives[i].name == name {
+			c.directives[i].sources = sources
+			return c
+		}
+	}
+	c.directives = append(c.directives, cspDirective{name: name, sources: sources})
+	return c
+}
+
+// Remove removes the directive of name.
+func (c *CSP) Remove(name string) *CSP {
+	c.directives = slices.DeleteFunc(c.directives, func(d cspDirective) bool { return d.name == name })
+	return c
+}
+
+// DefaultSrc sets default-src.
+func (c *CSP) DefaultSrc(sources ...CSPSource) *CSP { return c.Directive("default-src", sources...) }
+
+// ScriptSrc sets script-src.
+func (c *CSP) ScriptSrc(sources ...CSPSource) *CSP { return c.Directive("script-src", sources...) }
+
+// StyleSrc sets style-src.
+func (c *CSP) StyleSrc(sources ...CSPSource) *CSP { return c.Directive("style-src", sources...) }
+
+// ImgSrc sets img-src.
+func (c *CSP) ImgSrc(sources ...CSPSource) *CSP { return c.Directive("img-src", sources...) }
+
+// FontSrc sets font-src.
+func (c *CSP) FontSrc(sources ...CSPSource) *CSP { return c.Directive("font-src", sources...) }
+
+// ConnectSrc sets connect-src.
+func (c *CSP) ConnectSrc(sources ...CSPSource) *CSP { return c.Directive("connect-src", sources...) }
+
+// MediaSrc sets media-src.
+func (c *CSP) MediaSrc(sources ...CSPSource) *CSP { return c.Directive("media-src", sources...) }
+
+// ObjectSrc sets object-src.
+func (c *CSP) ObjectSrc(sources ...CSPSource) *CSP { return c.Directive("object-src", sources...) }
+
+// FrameSrc sets frame-src.
+func (c *CSP) FrameSrc(sources ...CSPSource) *CSP { return c.Directive("frame-src", sources...) }
+
+// WorkerSrc sets worker-src.
+func (c *CSP) WorkerSrc(sources ...CSPSource) *CSP { return c.Directive("worker-src", sources...) }
+
+// BaseURI sets base-uri.
+func (c *CSP) BaseURI(sources ...CSPSource) *CSP { return c.Directive("base-uri", sources...) }
+
+// FormAction sets form-action.
+func (c *CSP) FormAction(sources ...CSPSource) *CSP { return c.Directive("form-action", sources...) }
+
+// FrameAncestors sets frame-ancestors.
+func (c *CSP) FrameAncestors(sources ...CSPSource) *CSP {
+	return c.Directive("frame-ancestors", sources...)
+}
+
+// UpgradeInsecureRequests sets upgrade-insecure-requests.
+func (c *CSP) UpgradeInsecureRequests() *CSP { return c.Directive("upgrade-insecure-requests") }
+
+// ReportTo sets report-to with the group of the Reporting-Endpoints header.
+func (c *CSP) ReportTo(group string) *CSP { return c.Directive("report-to", CSPSource(group)) }
+
+// String returns the policy, CSPNonce is not replaced.
+func (c *CSP) String() string {
+	var b strings.Builder
+	for i, d := range c.directives {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(d.name)
+		for _, s := range d.sources {
+			b.WriteString(" ")
+			b.WriteString(string(s))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	opts := middleware.SecureHeadersOptions{
+		HSTSMaxAge:                2 * 365 * 24 * time.Hour,
+		HSTSIncludeSubDomains:     true,
+		HSTSPreload:               true,
+		ReferrerPolicy:            "no-referrer",
+		PermissionsPolicy:         "camera=(), microphone=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginEmbedderPolicy: "require-corp",
+		CSP: middleware.NewCSP().
+			DefaultSrc(middleware.CSPSelf).
+			ScriptSrc(middleware.CSPSelf, middleware.CSPNonce).
+			ImgSrc(middleware.CSPSelf, middleware.CSPData, "https://cdn.example.com").
+			UpgradeInsecureRequests(),
+	}
+	docs := opts
+	docs.CrossOriginEmbedderPolicy = ""
+	docs.CSP = opts.CSP.Clone().ScriptSrc(middleware.CSPSelf, middleware.CSPUnsafeInline, middleware.CSPNonce)
+	report := middleware.SecureHeadersOptions{CSP: middleware.NewCSP().DefaultSrc(middleware.CSPNone).ReportTo("csp"), CSPReportOnly: true}
+
+	nonceHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(middleware.GetCSPNonce(r.Context())))
+	}
+	r := michi.NewRouter()
+	r.Use(middleware.SecureHeadersWithOptions(opts))
+	r.HandleFunc("GET /", nonceHandler)
+	r.With(middleware.SecureHeadersWithOptions(docs)).HandleFunc("GET /docs", nonceHandler)
+	r.With(middleware.SecureHeadersWithOptions(report)).HandleFunc("GET /report", nonceHandler)
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+	}{
+		{
+			name: "router",
+			path: "/",
+			header: map[string]string{
+				"X-Content-Type-Options":              "nosniff",
+				"Strict-Transport-Security":           "max-age=63072000; includeSubDomains; preload",
+				"Referrer-Policy":                     "no-referrer",
+				"Permissions-Policy":                  "camera=(), microphone=()",
+				"Cross-Origin-Opener-Policy":          "same-origin",
+				"Cross-Origin-Embedder-Policy":        "require-corp",
+				"Content-Security-Policy":             "default-src 'self'; script-src 'self' 'nonce-{nonce}'; img-src 'self' data: https://cdn.example.com; upgrade-insecure-requests",
+				"Content-Security-Policy-Report-Only": "",
+			},
+		},
+		{
+			name: "relaxed by With",
+			path: "/docs",
+			header: map[string]string{
+				"X-Content-Type-Options":       "nosniff",
+				"Cross-Origin-Embedder-Policy": "",
+				"Content-Security-Policy":      "default-src 'self'; script-src 'self' 'unsafe-inline' 'nonce-{nonce}'; img-src 'self' data: https://cdn.example.com; upgrade-insecure-requests",
+			},
+		},
+		{
+			name: "report only by With",
+			path: "/report",
+			header: map[string]string{
+				"X-Content-Type-Options":              "nosniff",
+				"Strict-Transport-Security":           "",
+				"Referrer-Policy":                     "",
+				"Content-Security-Policy":             "",
+				"Content-Security-Policy-Report-Only": "default-src 'none'; report-to csp",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+tt.path, nil))
+			nonce := w.Body.String()
+			if nonce == "" {
+				t.Errorf("nonce got: empty want: nonce")
+			}
+			for name, want := range tt.header {
+				want = strings.ReplaceAll(want, "{nonce}", nonce)
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%v got: %v want: %v", name, got, want)
+				}
+			}
+		})
+	}
+
+	// nonces differ for each request
+	w1, w2 := httptest.NewRecorder(), httptest.NewRecorder()
+	r.ServeHTTP(w1, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	r.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	if w1.Body.String() == w2.Body.String() {
+		t.Errorf("nonces got: same %v want: different", w1.Body.String())
+	}
+}
+
+func TestSecureHeaders_Default(t *testing.T) {
+	// modifying the CSP of the defaults does not affect the others
+	custom := middleware.DefaultSecureHeadersOptions()
+	custom.CSP.ScriptSrc(middleware.CSPSelf, middleware.CSPUnsafeInline)
+	h := middleware.SecureHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if nonce := middleware.GetCSPNonce(r.Context()); nonce != "" {
+			t.Errorf("nonce got: %v want: empty", nonce)
+		}
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	want := map[string]string{
+		"X-Content-Type-Options":     "nosniff",
+		"Strict-Transport-Security":  "max-age=31536000",
+		"Referrer-Policy":            "strict-origin-when-cross-origin",
+		"Cross-Origin-Opener-Policy": "same-origin",
+		"Content-Security-Policy":    "default-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'self'",
+	}
+	for name, v := range want {
+		if got := w.Header().Get(name); got != v {
+			t.Errorf("%v got: %v want: %v", name, got, v)
+		}
+	}
+}
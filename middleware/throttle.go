@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ThrottleOptions are the options of ThrottleWithOptions.
+type ThrottleOptions struct {
+	// Limit is the maximum number of requests served concurrently
+	Limit int
+	// Backlog is the maximum number of requests waiting for a slot
+	Backlog int
+	// Timeout is the maximum time a request waits in the backlog
+	Timeout time.Duration
+	// StatusCode is the status code of rejected requests, 429 Too Many Requests if zero
+	StatusCode int
+	// RetryAfter is the Retry-After header of rejected requests, Timeout rounded up to seconds if zero
+	RetryAfter time.Duration
+	// Stats is called when a request is admitted or rejected
+	Stats func(ThrottleStats)
+}
+
+// ThrottleStats is the state of Throttle when a request is admitted or rejected.
+type ThrottleStats struct {
+	// InFlight is the number of requests being served
+	InFlight int
+	// Queued is the number of requests waiting in the backlog
+	Queued int
+	// Wait is the time the request waited in the backlog
+	Wait time.Duration
+	// Rejected is true if the request is rejected because the backlog is full or Timeout is exceeded
+	Rejected bool
+}
+
+// Throttle is a middleware that limits the number of requests served concurrently to limit.
+// Excess requests wait in the backlog up to timeout, and the requests overflowing the backlog
+// or exceeding timeout are rejected with 429 Too Many Requests and Retry-After.
+//
+// Each call of Throttle has its own limit, so it can limit a router or a group created by Route, Group or With.
+func Throttle(limit, backlog int, timeout time.Duration) func(http.Handler) http.Handler {
+	return ThrottleWithOptions(ThrottleOptions{Limit: limit, Backlog: backlog, Timeout: timeout})
+}
+
+// ThrottleWithOptions returns a Throttle middleware with the options.
+func ThrottleWithOptions(opts ThrottleOptions) func(http.Handler) http.Handler {
+	if opts.Limit < 1 {
+		panic("middleware: Throttle limit must be positive")
+	}
+	if opts.Backlog < 0 {
+		panic("middleware: Throttle backlog must not be negative")
+	}
+	if opts.StatusCode == 0 {
+		opts.StatusCode = http.StatusTooManyRequests
+	}
+	if opts.RetryAfter == 0 {
+		opts.RetryAfter = opts.Timeout
+	}
+	retryAfter := strconv.Itoa(max(1, int((opts.RetryAfter+time.Second-1)/time.Second)))
+	t := &throttle{
+		opts: opts,
+		// tokens are the slots of requests being served
+		tokens: make(chan struct{}, opts.Limit),
+		// admitted are the slots of requests being served or waiting
+		admitted: make(chan struct{}, opts.Limit+opts.Backlog),
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			select {
+			case t.admitted <- struct{}{}:
+			default:
+				t.stats(0, true)
+				t.reject(w, retryAfter)
+				return
+			}
+			defer func() { <-t.admitted }()
+
+			select {
+			case t.tokens <- struct{}{}:
+			default:
+				if !t.wait(r, start) {
+					t.stats(time.Since(start), true)
+					if r.Context().Err() == nil {
+						t.reject(w, retryAfter)
+					}
+					return
+				}
+			}
+			t.inFlight.Add(1)
+			t.stats(time.Since(start), false)
+			defer func() {
+				t.inFlight.Add(-1)
+				<-t.tokens
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type throttle struct {
+	opts     ThrottleOptions
+	tokens   chan struct{}
+	admitted chan struct{}
+	inFlight atomic.Int64
+	queued   atomic.Int64
+}
+
+// wait waits for a token in the backlog, it reports false if Timeout is exceeded or the request is canceled
+func (t *throttle) wait(r *http.Request, start time.Time) bool {
+	t.queued.Add(1)
+	defer t.queued.Add(-1)
+	timer := time.NewTimer(t.opts.Timeout - time.Since(start))
+	defer timer.Stop()
+	select {
+	case t.tokens <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (t *throttle) stats(wait time.Duration, rejected bool) {
+	if t.opts.Stats == nil {
+		return
+	}
+	t.opts.Stats(ThrottleStats{
+		InFlight: int(t.inFlight.Load()),
+		Queued:   int(t.queued.Load()),
+		Wait:     wait,
+		Rejected: rejected,
+	})
+}
+
+func (t *throttle) reject(w http.ResponseWriter, retryAfter string) {
+	w.Header().Set("Retry-After", retryAfter)
+	http.Error(w, http.StatusText(t.opts.StatusCode), t.opts.StatusCode)
+}
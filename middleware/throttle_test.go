@@ -0,0 +1,123 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestThrottleParallel(t *testing.T) {
+	const (
+		limit    = 5
+		backlog  = 20
+		requests = 200
+	)
+	var inFlight, maxInFlight, rejected atomic.Int64
+	release := make(chan struct{})
+	h := middleware.ThrottleWithOptions(middleware.ThrottleOptions{
+		Limit:   limit,
+		Backlog: backlog,
+		Timeout: 10 * time.Second,
+		Stats: func(s middleware.ThrottleStats) {
+			if s.InFlight > limit || s.Queued > backlog {
+				t.Errorf("stats got: %+v want: InFlight <= %d, Queued <= %d", s, limit, backlog)
+			}
+			if s.Rejected {
+				rejected.Add(1)
+			}
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		inFlight.Add(-1)
+	}))
+
+	var wg sync.WaitGroup
+	codes := make(chan int, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+			codes <- w.Code
+		}()
+	}
+	// the requests overflowing the limit and the backlog are rejected immediately
+	deadline := time.Now().Add(5 * time.Second)
+	for rejected.Load() < requests-limit-backlog && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	count := map[int]int{}
+	for code := range codes {
+		count[code]++
+	}
+	if count[http.StatusOK] != limit+backlog || count[http.StatusTooManyRequests] != requests-limit-backlog {
+		t.Errorf("status codes got: %v want: %d OK and %d Too Many Requests", count, limit+backlog, requests-limit-backlog)
+	}
+	if got := maxInFlight.Load(); got > limit {
+		t.Errorf("max in-flight got: %v want: <= %v", got, limit)
+	}
+}
+
+func TestThrottleTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var stats []middleware.ThrottleStats
+	var mu sync.Mutex
+	h := middleware.ThrottleWithOptions(middleware.ThrottleOptions{
+		Limit:      1,
+		Backlog:    1,
+		Timeout:    20 * time.Millisecond,
+		StatusCode: http.StatusServiceUnavailable,
+		Stats: func(s middleware.ThrottleStats) {
+			mu.Lock()
+			defer mu.Unlock()
+			stats = append(stats, s)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+		close(done)
+	}()
+	for {
+		mu.Lock()
+		n := len(stats)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	close(release)
+	<-done
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("StatusCode got: %v want: %v", w.Code, http.StatusServiceUnavailable)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After got: %v want: %v", got, "1")
+	}
+	if s := stats[1]; !s.Rejected || s.InFlight != 1 || s.Wait < 20*time.Millisecond {
+		t.Errorf("stats got: %+v want: rejected after waiting 20ms", s)
+	}
+}
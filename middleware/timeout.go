@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-michi/michi"
+)
+
+// TimeoutOptions are the options of TimeoutWithOptions.
+type TimeoutOptions struct {
+	// Handler responds when the handler exceeds the timeout without starting the response,
+	// 503 Service Unavailable as plain text if nil
+	Handler http.Handler
+}
+
+// Timeout is a middleware that cancels the request context with the deadline of d,
+// and responds 503 Service Unavailable if the handler exceeds it without starting the response.
+//
+// Unlike http.TimeoutHandler, the response is not buffered, so streaming handlers and http.Flusher work.
+// Once the response is started, Timeout waits for the handler, which must return when the context is done.
+// After the timeout response, writes of the handler fail with http.ErrHandlerTimeout,
+// and so do Hijack, SetReadDeadline, SetWriteDeadline and EnableFullDuplex of http.ResponseController.
+// The handler runs with a clone of the request, so that it does not change the request
+// and the route pattern read by the outer middlewares after the timeout.
+//
+// A Timeout added by With overrides the deadline of the Timeout added by Use,
+// and its TimeoutOptions.Handler if it is set:
+//
+//	r.Use(middleware.Timeout(5 * time.Second))
+//	r.With(middleware.Timeout(time.Minute)).HandleFunc("POST /reports", handler)
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return TimeoutWithOptions(d, TimeoutOptions{})
+}
+
+// TimeoutWithOptions returns a Timeout middleware with the options.
+// To respond 504 Gateway Timeout, set a handler such as render.Default.StatusHandler(http.StatusGatewayTimeout).
+func TimeoutWithOptions(d time.Duration, opts TimeoutOptions) func(http.Handler) http.Handler {
+	// the handler of an overriding Timeout replaces the outer one only if it is set
+	overrideHandler := opts.Handler
+	if opts.Handler == nil {
+		opts.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		})
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if st, ok := r.Context().Value(timeoutKey{}).(*timeoutState); ok {
+				ctx, cancel := st.override(r.Context(), d, overrideHandler)
+				defer cancel()
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			serveWithTimeout(w, r, next, d, opts.Handler)
+		})
+	}
+}
+
+type timeoutKey struct{}
+
+// timeoutState is the state of the outermost Timeout shared with the overriding Timeouts
+type timeoutState struct {
+	// base is cancelled when the request times out, it has no deadline so that the deadline can be extended
+	base  context.Context
+	start time.Time
+	// reset notifies the change of deadline
+	reset    chan struct{}
+	mu       sync.Mutex
+	deadline time.Time
+	// handler responds on timeout, it is the handler of the innermost Timeout setting it
+	handler http.Handler
+}
+
+// override sets the deadline of d from the start of the request and the timeout handler if it is not nil,
+// and returns the context having the values of ctx and the new deadline
+func (st *timeoutState) override(ctx context.Context, d time.Duration, handler http.Handler) (context.Context, context.CancelFunc) {
+	deadline := st.start.Add(d)
+	st.mu.Lock()
+	st.deadline = deadline
+	if handler != nil {
+		st.handler = handler
+	}
+	st.mu.Unlock()
+	select {
+	case st.reset <- struct{}{}:
+	default:
+	}
+	dctx, cancel := context.WithDeadline(st.base, deadline)
+	return valuesContext{Context: dctx, values: ctx}, cancel
+}
+
+func (st *timeoutState) getDeadline() time.Time {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.deadline
+}
+
+func (st *timeoutState) getHandler() http.Handler {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.handler
+}
+
+// valuesContext has the deadline and the cancellation of Context, and the values of values
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key any) any {
+	return c.values.Value(key)
+}
+
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, next http.Handler, d time.Duration, timeoutHandler http.Handler) {
+	base, cancelBase := context.WithCancel(r.Context())
+	defer cancelBase()
+	st := &timeoutState{base: base, start: time.Now(), reset: make(chan struct{}, 1), handler: timeoutHandler}
+	st.deadline = st.start.Add(d)
+	ctx, cancel := context.WithDeadline(base, st.deadline)
+	defer cancel()
+	ctx = context.WithValue(ctx, timeoutKey{}, st)
+	// the handler may outlive this function, so it gets its own request and route to write
+	ctx, restoreRoute := michi.DetachRoute(ctx)
+	defer restoreRoute()
+	req := r.Clone(ctx)
+
+	tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+	done := make(chan struct{})
+	panicChan := make(chan any, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+			}
+		}()
+		next.ServeHTTP(tw, req)
+		close(done)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+			return
+		case <-st.reset:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(time.Until(st.getDeadline()))
+		case <-timer.C:
+			tw.mu.Lock()
+			if tw.started {
+				tw.mu.Unlock()
+				// the response can not be replaced, wait for the handler which sees the deadline
+				select {
+				case p := <-panicChan:
+					panic(p)
+				case <-done:
+				}
+				return
+			}
+			tw.timedOut = true
+			tw.mu.Unlock()
+			cancelBase()
+			st.getHandler().ServeHTTP(w, r)
+			return
+		}
+	}
+}
+
+// timeoutWriter passes the response through to w until the request times out.
+// The handler writes the headers to its own map, so that the timeout response does not race with the handler.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	header   http.Header
+	started  bool
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// start copies the headers to w before writing the response, it must be called with mu locked
+func (tw *timeoutWriter) start() {
+	dst := tw.w.Header()
+	for k := range dst {
+		if _, ok := tw.header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.started {
+		return
+	}
+	tw.start()
+	// 1xx informational responses are followed by the final status
+	tw.started = statusCode >= 200
+	tw.w.WriteHeader(statusCode)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.started {
+		tw.start()
+		tw.started = true
+	}
+	return tw.w.Write(b)
+}
+
+// Flush flushes the response to the client, so that streaming handlers work
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.started {
+		tw.start()
+		tw.started = true
+	}
+	_ = http.NewResponseController(tw.w).Flush()
+}
+
+// Hijack hijacks the connection unless the request has timed out.
+// The timeout response is not written after Hijack.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := http.NewResponseController(tw.w).Hijack()
+	if err == nil {
+		tw.started = true
+	}
+	return conn, rw, err
+}
+
+// SetReadDeadline sets the read deadline of the connection unless the request has timed out
+func (tw *timeoutWriter) SetReadDeadline(deadline time.Time) error {
+	return tw.control(func(rc *http.ResponseController) error { return rc.SetReadDeadline(deadline) })
+}
+
+// SetWriteDeadline sets the write deadline of the connection unless the request has timed out
+func (tw *timeoutWriter) SetWriteDeadline(deadline time.Time) error {
+	return tw.control(func(rc *http.ResponseController) error { return rc.SetWriteDeadline(deadline) })
+}
+
+// EnableFullDuplex enables the full duplex of HTTP/1 unless the request has timed out
+func (tw *timeoutWriter) EnableFullDuplex() error {
+	return tw.control(func(rc *http.ResponseController) error { return rc.EnableFullDuplex() })
+}
+
+// control calls f with the http.ResponseController of w, or returns http.ErrHandlerTimeout after the timeout.
+// timeoutWriter has no Unwrap, so that the handler can not reach w after the timeout.
+func (tw *timeoutWriter) control(f func(rc *http.ResponseController) error) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	return f(http.NewResponseController(tw.w))
+}
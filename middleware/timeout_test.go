@@ -0,0 +1,177 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/middleware"
+)
+
+func TestTimeout(t *testing.T) {
+	writeErr := make(chan error, 1)
+	r := michi.NewRouter()
+	r.Use(middleware.Timeout(20 * time.Millisecond))
+	r.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Slow", "1")
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := w.Write([]byte("late"))
+		writeErr <- err
+	})
+	r.HandleFunc("GET /stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush got: %v", err)
+		}
+		<-r.Context().Done()
+		w.Write([]byte("b"))
+	})
+	r.With(middleware.Timeout(200*time.Millisecond)).HandleFunc("GET /override", func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		if d := time.Until(deadline); d < 100*time.Millisecond {
+			t.Errorf("deadline got: %v want: about 200ms", d)
+		}
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("ok"))
+	})
+	r.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/slow", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("StatusCode got: %v want: %v", w.Code, http.StatusServiceUnavailable)
+		}
+		if w.Header().Get("X-Slow") != "" {
+			t.Error("headers of the timed out handler must not be written")
+		}
+		if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("Write after timeout got: %v want: %v", err, http.ErrHandlerTimeout)
+		}
+	})
+	t.Run("streaming response is not replaced", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/stream", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "ab" || !w.Flushed {
+			t.Errorf("response got: %v %q flushed: %v want: 200 \"ab\" flushed: true", w.Code, w.Body.String(), w.Flushed)
+		}
+	})
+	t.Run("override by With", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/override", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Errorf("response got: %v %q want: 200 \"ok\"", w.Code, w.Body.String())
+		}
+	})
+	t.Run("panic", func(t *testing.T) {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Errorf("recover got: %v want: boom", v)
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://example.com/panic", nil))
+	})
+}
+
+func TestTimeout_HandlerOutlivesTimeout(t *testing.T) {
+	finished := make(chan error, 1)
+	var pattern string
+	r := michi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req)
+			pattern = michi.RoutePattern(req)
+		})
+	})
+	r.Use(middleware.Timeout(10 * time.Millisecond))
+	r.Route("/slow", func(r *michi.Router) {
+		// the route pattern is recorded after the timeout, while the outer middleware reads it
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				<-req.Context().Done()
+				time.Sleep(10 * time.Millisecond)
+				next.ServeHTTP(w, req)
+			})
+		})
+		r.HandleFunc("GET /{id}", func(w http.ResponseWriter, req *http.Request) {
+			req.Header.Set("X-Late", "1")
+			_, err := w.Write([]byte("late"))
+			finished <- err
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/slow/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("StatusCode got: %v want: %v", w.Code, http.StatusServiceUnavailable)
+	}
+	if pattern != "" && pattern != "GET /slow/{id}" {
+		t.Errorf("pattern got: %v want: empty or GET /slow/{id}", pattern)
+	}
+	if err := <-finished; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("Write after timeout got: %v want: %v", err, http.ErrHandlerTimeout)
+	}
+	if req.Header.Get("X-Late") != "" {
+		t.Error("the handler must not change the request of the outer middlewares")
+	}
+}
+
+func TestTimeout_Options(t *testing.T) {
+	gatewayTimeout := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+	r := michi.NewRouter()
+	r.Use(middleware.Timeout(time.Second))
+	r.With(middleware.TimeoutWithOptions(10*time.Millisecond, middleware.TimeoutOptions{Handler: gatewayTimeout})).HandleFunc("GET /gateway", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	r.With(middleware.Timeout(10*time.Millisecond)).HandleFunc("GET /default", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	r.HandleFunc("GET /hijack", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+			t.Errorf("Hijack got: %v want: nil", err)
+		}
+	})
+	lateErrs := make(chan []error, 1)
+	r.With(middleware.Timeout(10*time.Millisecond)).HandleFunc("GET /late", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		rc := http.NewResponseController(w)
+		_, _, err := rc.Hijack()
+		lateErrs <- []error{err, rc.SetWriteDeadline(time.Now()), rc.SetReadDeadline(time.Now()), rc.EnableFullDuplex()}
+	})
+
+	for path, want := range map[string]int{"/gateway": http.StatusGatewayTimeout, "/default": http.StatusServiceUnavailable} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil))
+		if w.Code != want {
+			t.Errorf("%v StatusCode got: %v want: %v", path, w.Code, want)
+		}
+	}
+
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/hijack", nil))
+	if !w.hijacked {
+		t.Errorf("hijacked got: %v want: true", w.hijacked)
+	}
+
+	// the connection is not reachable after the timeout
+	w = &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/late", nil))
+	for _, err := range <-lateErrs {
+		if !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("after timeout got: %v want: %v", err, http.ErrHandlerTimeout)
+		}
+	}
+	if w.hijacked {
+		t.Errorf("hijacked got: %v want: false", w.hijacked)
+	}
+}
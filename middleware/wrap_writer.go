@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// WrapResponseWriter is a http.ResponseWriter recording the status code, the bytes written and the time of the first write.
+//
+// It has exactly the optional interfaces of the original writer among http.Flusher, http.Hijacker and io.ReaderFrom,
+// so wrapping a writer does not disable streaming, WebSocket upgrades or sendfile.
+// The other features such as deadlines are available by http.ResponseController with Unwrap.
+type WrapResponseWriter interface {
+	http.ResponseWriter
+	// Status returns the status code written, or 0 if the response is not started
+	Status() int
+	// BytesWritten returns the number of bytes of the body written
+	BytesWritten() int64
+	// FirstWrite returns the time the response is started, or the zero time if it is not started
+	FirstWrite() time.Time
+	// Unwrap returns the original http.ResponseWriter
+	Unwrap() http.ResponseWriter
+}
+
+// NewWrapResponseWriter wraps w.
+func NewWrapResponseWriter(w http.ResponseWriter) WrapResponseWriter {
+	b := &basicWriter{ResponseWriter: w}
+	_, flusher := w.(http.Flusher)
+	_, hijacker := w.(http.Hijacker)
+	_, readerFrom := w.(io.ReaderFrom)
+	switch {
+	case flusher && hijacker && readerFrom:
+		return &flushHijackReadFromWriter{b}
+	case flusher && hijacker:
+		return &flushHijackWriter{b}
+	case flusher && readerFrom:
+		return &flushReadFromWriter{b}
+	case hijacker && readerFrom:
+		return &hijackReadFromWriter{b}
+	case flusher:
+		return &flushWriter{b}
+	case hijacker:
+		return &hijackWriter{b}
+	case readerFrom:
+		return &readFromWriter{b}
+	default:
+		return b
+	}
+}
+
+// basicWriter has only the methods of http.ResponseWriter
+type basicWriter struct {
+	http.ResponseWriter
+	status     int
+	bytes      int64
+	firstWrite time.Time
+}
+
+func (b *basicWriter) WriteHeader(statusCode int) {
+	// 1xx informational responses are followed by the final status
+	if b.status == 0 && statusCode >= 200 {
+		b.status = statusCode
+		b.firstWrite = time.Now()
+	}
+	b.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (b *basicWriter) Write(p []byte) (int, error) {
+	b.start()
+	n, err := b.ResponseWriter.Write(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// start records 200 OK if the response is not started, because it is written implicitly
+func (b *basicWriter) start() {
+	if b.status == 0 {
+		b.status = http.StatusOK
+		b.firstWrite = time.Now()
+	}
+}
+
+func (b *basicWriter) Status() int {
+	return b.status
+}
+
+func (b *basicWriter) BytesWritten() int64 {
+	return b.bytes
+}
+
+func (b *basicWriter) FirstWrite() time.Time {
+	return b.firstWrite
+}
+
+func (b *basicWriter) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+func (b *basicWriter) flush() {
+	b.start()
+	b.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (b *basicWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return b.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (b *basicWriter) readFrom(r io.Reader) (int64, error) {
+	b.start()
+	n, err := b.ResponseWriter.(io.ReaderFrom).ReadFrom(r)
+	b.bytes += n
+	return n, err
+}
+
+// the writers below have the combinations of http.Flusher, http.Hijacker and io.ReaderFrom
+
+type flushWriter struct{ *basicWriter }
+
+func (w *flushWriter) Flush() { w.flush() }
+
+type hijackWriter struct{ *basicWriter }
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type readFromWriter struct{ *basicWriter }
+
+func (w *readFromWriter) ReadFrom(r io.Reader) (int64, error) { return w.readFrom(r) }
+
+type flushHijackWriter struct{ *basicWriter }
+
+func (w *flushHijackWriter) Flush()                                       { w.flush() }
+func (w *flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type flushReadFromWriter struct{ *basicWriter }
+
+func (w *flushReadFromWriter) Flush()                              { w.flush() }
+func (w *flushReadFromWriter) ReadFrom(r io.Reader) (int64, error) { return w.readFrom(r) }
+
+type hijackReadFromWriter struct{ *basicWriter }
+
+func (w *hijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w *hijackReadFromWriter) ReadFrom(r io.Reader) (int64, error)          { return w.readFrom(r) }
+
+type flushHijackReadFromWriter struct{ *basicWriter }
+
+func (w *flushHijackReadFromWriter) Flush()                                       { w.flush() }
+func (w *flushHijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w *flushHijackReadFromWriter) ReadFrom(r io.Reader) (int64, error)          { return w.readFrom(r) }
@@ -0,0 +1,147 @@
+package middleware_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-michi/michi/middleware"
+)
+
+// plainWriter has only the methods of http.ResponseWriter, and records the calls of the optional interfaces
+type plainWriter struct {
+	rec      *httptest.ResponseRecorder
+	flushed  bool
+	hijacked bool
+	readFrom bool
+}
+
+func (w *plainWriter) Header() http.Header         { return w.rec.Header() }
+func (w *plainWriter) Write(b []byte) (int, error) { return w.rec.Write(b) }
+func (w *plainWriter) WriteHeader(statusCode int)  { w.rec.WriteHeader(statusCode) }
+func (w *plainWriter) flush()                      { w.flushed = true }
+func (w *plainWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+func (w *plainWriter) readFromReader(r io.Reader) (int64, error) {
+	w.readFrom = true
+	return io.Copy(w.rec.Body, r)
+}
+
+// the writers below have the combinations of the optional interfaces
+
+type fWriter struct{ *plainWriter }
+
+func (w fWriter) Flush() { w.flush() }
+
+type hWriter struct{ *plainWriter }
+
+func (w hWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type rWriter struct{ *plainWriter }
+
+func (w rWriter) ReadFrom(r io.Reader) (int64, error) { return w.readFromReader(r) }
+
+type fhWriter struct{ *plainWriter }
+
+func (w fhWriter) Flush()                                       { w.flush() }
+func (w fhWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type frWriter struct{ *plainWriter }
+
+func (w frWriter) Flush()                              { w.flush() }
+func (w frWriter) ReadFrom(r io.Reader) (int64, error) { return w.readFromReader(r) }
+
+type hrWriter struct{ *plainWriter }
+
+func (w hrWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w hrWriter) ReadFrom(r io.Reader) (int64, error)          { return w.readFromReader(r) }
+
+type fhrWriter struct{ *plainWriter }
+
+func (w fhrWriter) Flush()                                       { w.flush() }
+func (w fhrWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+func (w fhrWriter) ReadFrom(r io.Reader) (int64, error)          { return w.readFromReader(r) }
+
+func TestWrapResponseWriter(t *testing.T) {
+	tests := []struct {
+		name                          string
+		writer                        func(p *plainWriter) http.ResponseWriter
+		flusher, hijacker, readerFrom bool
+	}{
+		{name: "none", writer: func(p *plainWriter) http.ResponseWriter { return p }},
+		{name: "Flusher", writer: func(p *plainWriter) http.ResponseWriter { return fWriter{p} }, flusher: true},
+		{name: "Hijacker", writer: func(p *plainWriter) http.ResponseWriter { return hWriter{p} }, hijacker: true},
+		{name: "ReaderFrom", writer: func(p *plainWriter) http.ResponseWriter { return rWriter{p} }, readerFrom: true},
+		{name: "Flusher and Hijacker", writer: func(p *plainWriter) http.ResponseWriter { return fhWriter{p} }, flusher: true, hijacker: true},
+		{name: "Flusher and ReaderFrom", writer: func(p *plainWriter) http.ResponseWriter { return frWriter{p} }, flusher: true, readerFrom: true},
+		{name: "Hijacker and ReaderFrom", writer: func(p *plainWriter) http.ResponseWriter { return hrWriter{p} }, hijacker: true, readerFrom: true},
+		{name: "all", writer: func(p *plainWriter) http.ResponseWriter { return fhrWriter{p} }, flusher: true, hijacker: true, readerFrom: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &plainWriter{rec: httptest.NewRecorder()}
+			orig := tt.writer(p)
+			ww := middleware.NewWrapResponseWriter(orig)
+
+			f, flusher := ww.(http.Flusher)
+			h, hijacker := ww.(http.Hijacker)
+			rf, readerFrom := ww.(io.ReaderFrom)
+			if flusher != tt.flusher || hijacker != tt.hijacker || readerFrom != tt.readerFrom {
+				t.Fatalf("interfaces got: Flusher %v Hijacker %v ReaderFrom %v want: %v %v %v",
+					flusher, hijacker, readerFrom, tt.flusher, tt.hijacker, tt.readerFrom)
+			}
+			if ww.Unwrap() != orig {
+				t.Error("Unwrap must return the original writer")
+			}
+			if ww.Status() != 0 || !ww.FirstWrite().IsZero() {
+				t.Errorf("Status got: %v FirstWrite got: %v want: not started", ww.Status(), ww.FirstWrite())
+			}
+
+			ww.WriteHeader(http.StatusCreated)
+			ww.Write([]byte("abc"))
+			if flusher {
+				f.Flush()
+				if !p.flushed {
+					t.Error("Flush must be passed through")
+				}
+			}
+			if hijacker {
+				h.Hijack()
+				if !p.hijacked {
+					t.Error("Hijack must be passed through")
+				}
+			}
+			want := int64(3)
+			if readerFrom {
+				rf.ReadFrom(strings.NewReader("de"))
+				if !p.readFrom {
+					t.Error("ReadFrom must be passed through")
+				}
+				want += 2
+			}
+			if ww.Status() != http.StatusCreated || ww.FirstWrite().IsZero() {
+				t.Errorf("Status got: %v want: %v", ww.Status(), http.StatusCreated)
+			}
+			if ww.BytesWritten() != want {
+				t.Errorf("BytesWritten got: %v want: %v", ww.BytesWritten(), want)
+			}
+		})
+	}
+}
+
+func TestWrapResponseWriterImplicitStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ww := middleware.NewWrapResponseWriter(rec)
+	if err := http.NewResponseController(ww).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if ww.Status() != http.StatusOK || !rec.Flushed {
+		t.Errorf("Status got: %v Flushed got: %v want: 200 true", ww.Status(), rec.Flushed)
+	}
+}
@@ -1,6 +1,8 @@
 package michi
 
 import (
+	"fmt"
+	"net/http"
 	"strings"
 )
 
@@ -17,6 +19,14 @@ func methodAndPath(pattern string) (string, string) {
 	return method, rest
 }
 
+// exactPath appends {$} to the path ending with a slash, so that it matches only the path itself
+func exactPath(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path + "{$}"
+	}
+	return path
+}
+
 func joinMethodAndPath(method, path string) string {
 	if method == "" {
 		return path
@@ -28,3 +38,104 @@ func joinPathAndPattern(path, pattern string) string {
 	fullPath := path + pattern
 	return strings.ReplaceAll(fullPath, "//", "/")
 }
+
+// splitHostAndPath splits the path of a pattern into the host and the path
+func splitHostAndPath(path string) (string, string) {
+	i := strings.IndexByte(path, '/')
+	if i < 0 {
+		return path, ""
+	}
+	return path[:i], path[i:]
+}
+
+// segments splits the path into segments and reports whether the path ends with a trailing slash
+func segments(path string) ([]string, bool) {
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if segs[len(segs)-1] == "" {
+		return segs[:len(segs)-1], true
+	}
+	return segs, false
+}
+
+func isWildcard(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func isMultiWildcard(segment string) bool {
+	return isWildcard(segment) && strings.HasSuffix(segment, "...}")
+}
+
+// mismatchPath returns the reason why the path of the pattern does not match the request path.
+// It returns an empty string if the pattern matches the request path.
+func mismatchPath(pattern, path string) string {
+	pSegs, pSlash := segments(pattern)
+	exact := false
+	if n := len(pSegs); n > 0 && pSegs[n-1] == "{$}" {
+		pSegs, pSlash, exact = pSegs[:n-1], true, true
+	}
+	rSegs, rSlash := segments(path)
+	for i, seg := range pSegs {
+		if isMultiWildcard(seg) {
+			if i < len(rSegs) || rSlash {
+				return ""
+			}
+			return "missing trailing slash"
+		}
+		if i >= len(rSegs) {
+			return fmt.Sprintf("missing segment %s", seg)
+		}
+		if !isWildcard(seg) && seg != rSegs[i] {
+			return fmt.Sprintf("segment %q does not match %q", rSegs[i], seg)
+		}
+	}
+	switch {
+	case len(rSegs) > len(pSegs) && exact:
+		return "{$} matches only the path ending at the trailing slash"
+	case len(rSegs) > len(pSegs) && !pSlash:
+		return "pattern without trailing slash matches only the exact path"
+	case len(rSegs) == len(pSegs) && pSlash && !rSlash:
+		return "missing trailing slash"
+	case len(rSegs) == len(pSegs) && !pSlash && rSlash:
+		return "unexpected trailing slash"
+	}
+	return ""
+}
+
+// methodMatches reports whether the method of a pattern matches the request method.
+// GET pattern also matches HEAD request like http.ServeMux.
+func methodMatches(patternMethod, method string) bool {
+	return patternMethod == "" || patternMethod == method || (patternMethod == http.MethodGet && method == http.MethodHead)
+}
+
+// pathCovers reports whether the path of pattern a matches every request path matched by pattern b
+func pathCovers(a, b string) bool {
+	aSegs, aSlash := segments(a)
+	aExact := false
+	if n := len(aSegs); n > 0 && aSegs[n-1] == "{$}" {
+		aSegs, aSlash, aExact = aSegs[:n-1], true, true
+	}
+	bSegs, bSlash := segments(b)
+	bExact := false
+	if n := len(bSegs); n > 0 && bSegs[n-1] == "{$}" {
+		bSegs, bSlash, bExact = bSegs[:n-1], true, true
+	}
+	for i, seg := range aSegs {
+		if isMultiWildcard(seg) {
+			return len(bSegs) > i || bSlash
+		}
+		if i >= len(bSegs) || isMultiWildcard(bSegs[i]) {
+			return false
+		}
+		if !isWildcard(seg) && (isWildcard(bSegs[i]) || seg != bSegs[i]) {
+			return false
+		}
+	}
+	switch {
+	case aExact:
+		return bExact && len(bSegs) == len(aSegs)
+	case aSlash:
+		return len(bSegs) > len(aSegs) || bSlash
+	default:
+		return len(bSegs) == len(aSegs) && !bSlash
+	}
+}
@@ -84,3 +84,44 @@ func Test_methodAndPath(t *testing.T) {
 		})
 	}
 }
+
+func Test_exactPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "/",
+			path: "/",
+			want: "/{$}",
+		},
+		{
+			name: "/a",
+			path: "/a",
+			want: "/a",
+		},
+		{
+			name: "/a/",
+			path: "/a/",
+			want: "/a/{$}",
+		},
+		{
+			name: "/a/{$}",
+			path: "/a/{$}",
+			want: "/a/{$}",
+		},
+		{
+			name: "/a/{b...}",
+			path: "/a/{b...}",
+			want: "/a/{b...}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exactPath(tt.path); got != tt.want {
+				t.Errorf("exactPath got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+)
+
+// JSON encodes values as JSON, and problems as application/problem+json.
+type JSON struct {
+	// Indent is the indent of each level, values are not indented if empty
+	Indent string
+}
+
+// ContentType returns "application/json"
+func (JSON) ContentType() string {
+	return "application/json"
+}
+
+// ProblemContentType returns "application/problem+json"
+func (JSON) ProblemContentType() string {
+	return "application/problem+json"
+}
+
+// Encode encodes v as JSON
+func (e JSON) Encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", e.Indent)
+	if p, ok := v.(*Problem); ok {
+		v = problemJSON(p)
+	}
+	return enc.Encode(v)
+}
+
+// problemJSON returns the members of p with the extension members
+func problemJSON(p *Problem) map[string]any {
+	m := maps.Clone(p.Extensions)
+	if m == nil {
+		m = map[string]any{}
+	}
+	for k, v := range map[string]any{"type": p.Type, "title": p.Title, "detail": p.Detail, "instance": p.Instance} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	return m
+}
+
+// XML encodes values as XML, and problems as application/problem+xml.
+type XML struct {
+	// Indent is the indent of each level, values are not indented if empty
+	Indent string
+}
+
+// ContentType returns "application/xml"
+func (XML) ContentType() string {
+	return "application/xml"
+}
+
+// ProblemContentType returns "application/problem+xml"
+func (XML) ProblemContentType() string {
+	return "application/problem+xml"
+}
+
+// Encode encodes v as XML
+func (e XML) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", e.Indent)
+	if p, ok := v.(*Problem); ok {
+		v = struct {
+			XMLName xml.Name `xml:"urn:ietf:rfc:7807 problem"`
+			*Problem
+		}{Problem: p}
+	}
+	return enc.Encode(v)
+}
+
+// Text encodes values as plain text.
+type Text struct{}
+
+// ContentType returns "text/plain"
+func (Text) ContentType() string {
+	return "text/plain"
+}
+
+// Encode writes strings and []byte as is, and the other values formatted by fmt.Fprint
+func (Text) Encode(w io.Writer, v any) error {
+	var err error
+	switch v := v.(type) {
+	case string:
+		_, err = io.WriteString(w, v)
+	case []byte:
+		_, err = w.Write(v)
+	default:
+		_, err = fmt.Fprint(w, v)
+	}
+	return err
+}
+
+// HTML executes the template with values.
+type HTML struct {
+	Template *template.Template
+	// Name is the name of the template to execute, Template is executed if empty
+	Name string
+}
+
+// ContentType returns "text/html"
+func (HTML) ContentType() string {
+	return "text/html"
+}
+
+// Encode executes the template with v
+func (e HTML) Encode(w io.Writer, v any) error {
+	if e.Name == "" {
+		return e.Template.Execute(w, v)
+	}
+	return e.Template.ExecuteTemplate(w, e.Name, v)
+}
@@ -0,0 +1,250 @@
+// Package render renders responses with the encoder negotiated by the Accept header.
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		render.Render(w, r, http.StatusOK, user)
+//	}
+//
+// Errors are rendered as RFC 9457 problem details, and Renderer.HandleErrors renders
+// the 404 and 405 responses of michi.Router with the same negotiation.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-michi/michi"
+)
+
+// Encoder encodes a value to the response body.
+type Encoder interface {
+	// ContentType returns the media type of the encoded body such as "application/json"
+	ContentType() string
+	Encode(w io.Writer, v any) error
+}
+
+// ProblemEncoder is an Encoder having a dedicated media type for Problem such as "application/problem+json".
+type ProblemEncoder interface {
+	Encoder
+	ProblemContentType() string
+}
+
+// Renderer renders values with the encoder negotiated by the Accept header.
+type Renderer struct {
+	// encoders are in the order of preference
+	encoders []Encoder
+}
+
+// New creates a Renderer with the encoders in the order of preference.
+// The first encoder is used if the request has no Accept header.
+func New(encoders ...Encoder) *Renderer {
+	if len(encoders) == 0 {
+		panic("render: at least one encoder is required")
+	}
+	return &Renderer{encoders: encoders}
+}
+
+// Default is the Renderer used by the package functions, it prefers JSON, then XML and plain text.
+var Default = New(JSON{}, XML{}, Text{})
+
+// Render renders v with Default.
+func Render(w http.ResponseWriter, r *http.Request, status int, v any) {
+	Default.Render(w, r, status, v)
+}
+
+// Error renders the problem of the status and detail with Default.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	Default.Problem(w, r, NewProblem(status, detail))
+}
+
+// Render encodes v with the negotiated encoder and writes it with the status.
+// If the request accepts none of the encoders, the problem of 406 Not Acceptable is rendered instead.
+// If encoding fails, nothing is written but 500 Internal Server Error.
+func (rn *Renderer) Render(w http.ResponseWriter, r *http.Request, status int, v any) {
+	enc, ok := rn.Negotiate(r)
+	if !ok {
+		rn.Problem(w, r, NewProblem(http.StatusNotAcceptable, ""))
+		return
+	}
+	rn.write(w, enc, enc.ContentType(), status, v)
+}
+
+// Problem renders p with the negotiated encoder, using the problem media type if the encoder has it.
+// The status code is p.Status, or 500 Internal Server Error if it is not set.
+// The error is rendered with the first encoder if the request accepts none of them,
+// because replacing its status with 406 Not Acceptable would hide the error.
+// p is not modified, so a Problem can be shared by requests.
+func (rn *Renderer) Problem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	cp := *p
+	if cp.Status == 0 {
+		cp.Status = http.StatusInternalServerError
+	}
+	if cp.Title == "" {
+		cp.Title = http.StatusText(cp.Status)
+	}
+	enc, _ := rn.Negotiate(r)
+	contentType := enc.ContentType()
+	if pe, ok := enc.(ProblemEncoder); ok {
+		contentType = pe.ProblemContentType()
+	}
+	rn.write(w, enc, contentType, cp.Status, &cp)
+}
+
+// StatusHandler returns a handler rendering the problem of the status.
+func (rn *Renderer) StatusHandler(status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rn.Problem(w, r, NewProblem(status, ""))
+	})
+}
+
+// HandleErrors renders the 404 and 405 responses of the router tree as problems.
+func (rn *Renderer) HandleErrors(r *michi.Router) {
+	r.NotFound(rn.StatusHandler(http.StatusNotFound))
+	r.MethodNotAllowed(rn.StatusHandler(http.StatusMethodNotAllowed))
+}
+
+func (rn *Renderer) write(w http.ResponseWriter, enc Encoder, contentType string, status int, v any) {
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, v); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+// Negotiate returns the encoder for the Accept header of the request.
+// It reports false if the request accepts none of the encoders, and returns the first encoder.
+// A media range with q=0 excludes the encoders it matches from the less or equally specific media ranges,
+// so "application/json;q=0, */*" does not choose JSON.
+func (rn *Renderer) Negotiate(r *http.Request) (Encoder, bool) {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return rn.encoders[0], true
+	}
+	ranges := parseAccept(strings.Join(accept, ","))
+	var excluded []mediaRange
+	for _, mr := range ranges {
+		if mr.q == 0 {
+			excluded = append(excluded, mr)
+		}
+	}
+	for _, mr := range ranges {
+		if mr.q == 0 {
+			continue
+		}
+		for _, enc := range rn.encoders {
+			contentTypes := []string{enc.ContentType()}
+			if pe, ok := enc.(ProblemEncoder); ok {
+				contentTypes = append(contentTypes, pe.ProblemContentType())
+			}
+			if slices.ContainsFunc(contentTypes, mr.matches) && !isExcluded(excluded, mr, contentTypes) {
+				return enc, true
+			}
+		}
+	}
+	return rn.encoders[0], false
+}
+
+// isExcluded reports whether a media range of excluded, as specific as mr or more, matches one of contentTypes
+func isExcluded(excluded []mediaRange, mr mediaRange, contentTypes []string) bool {
+	for _, ex := range excluded {
+		if ex.specificity() >= mr.specificity() && slices.ContainsFunc(contentTypes, ex.matches) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaRange is a media range of the Accept header
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func (mr mediaRange) matches(contentType string) bool {
+	typ, subtype, _ := strings.Cut(contentType, "/")
+	return (mr.typ == "*" || mr.typ == typ) && (mr.subtype == "*" || mr.subtype == subtype)
+}
+
+// specificity orders exact media types before type/* and */*
+func (mr mediaRange) specificity() int {
+	switch {
+	case mr.typ == "*":
+		return 0
+	case mr.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseAccept parses the Accept header into media ranges ordered by preference
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok {
+			continue
+		}
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// Problem is a problem details object defined by RFC 9457.
+type Problem struct {
+	// Type is a URI reference identifying the problem type, "about:blank" if empty
+	Type string `json:"type,omitempty" xml:"type,omitempty"`
+	// Title is a short summary of the problem type
+	Title string `json:"title,omitempty" xml:"title,omitempty"`
+	// Status is the http status code
+	Status int `json:"status,omitempty" xml:"status,omitempty"`
+	// Detail is an explanation specific to this occurrence of the problem
+	Detail string `json:"detail,omitempty" xml:"detail,omitempty"`
+	// Instance is a URI reference identifying this occurrence of the problem
+	Instance string `json:"instance,omitempty" xml:"instance,omitempty"`
+	// Extensions are the extension members, they are rendered only in JSON
+	Extensions map[string]any `json:"-" xml:"-"`
+}
+
+// NewProblem creates a Problem of the status with the status text as the title.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// String returns the problem as text such as "404 Not Found: detail".
+func (p *Problem) String() string {
+	s := fmt.Sprintf("%d %s", p.Status, p.Title)
+	if p.Detail != "" {
+		s += ": " + p.Detail
+	}
+	return s
+}
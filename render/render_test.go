@@ -0,0 +1,229 @@
+package render_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-michi/michi"
+	"github.com/go-michi/michi/render"
+)
+
+func TestRender(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+	rn := render.New(render.JSON{}, render.XML{}, render.Text{}, render.HTML{Template: template.Must(template.New("").Parse("<p>{{.Name}}</p>"))})
+	tests := []struct {
+		name            string
+		accept          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "no Accept",
+			accept:          "",
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/json",
+			wantBody:        "{\"name\":\"\\u003ca\\u003e\"}\n",
+		},
+		{
+			name:            "xml",
+			wantStatus:      http.StatusCreated,
+			accept:          "application/xml",
+			wantContentType: "application/xml",
+			wantBody:        "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<user><name>&lt;a&gt;</name></user>",
+		},
+		{
+			name:            "q-value",
+			wantStatus:      http.StatusCreated,
+			accept:          "application/json;q=0.5, text/html",
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<p>&lt;a&gt;</p>",
+		},
+		{
+			name:            "specific type before wildcard with the same q-value",
+			wantStatus:      http.StatusCreated,
+			accept:          "*/*, text/plain",
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "{<a>}",
+		},
+		{
+			name:            "type wildcard",
+			wantStatus:      http.StatusCreated,
+			accept:          "image/png, text/*;q=0.8",
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "{<a>}",
+		},
+		{
+			name:            "excluded by q=0 from wildcard",
+			accept:          "application/json;q=0, */*",
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/xml",
+			wantBody:        "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<user><name>&lt;a&gt;</name></user>",
+		},
+		{
+			name:            "specific type before wildcard with q=0",
+			accept:          "*/*;q=0, text/plain",
+			wantStatus:      http.StatusCreated,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "{<a>}",
+		},
+		{
+			name:            "not acceptable",
+			accept:          "image/png, application/json;q=0",
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/problem+json",
+			wantBody:        "{\"status\":406,\"title\":\"Not Acceptable\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			rn.Render(w, r, http.StatusCreated, user{Name: "<a>"})
+			if w.Code != tt.wantStatus {
+				t.Errorf("StatusCode got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type got: %v want: %v", got, tt.wantContentType)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Body got: %q want: %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRenderEncodeError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	w := httptest.NewRecorder()
+	render.Render(w, r, http.StatusOK, make(chan int))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("StatusCode got: %v want: %v", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestProblem(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		problem         *render.Problem
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "json",
+			accept:          "application/problem+json",
+			problem:         &render.Problem{Status: http.StatusConflict, Detail: "already exists", Extensions: map[string]any{"id": 1}},
+			wantStatus:      http.StatusConflict,
+			wantContentType: "application/problem+json",
+			wantBody:        "{\"detail\":\"already exists\",\"id\":1,\"status\":409,\"title\":\"Conflict\"}\n",
+		},
+		{
+			name:            "xml",
+			accept:          "application/xml",
+			problem:         render.NewProblem(http.StatusBadRequest, "invalid"),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/problem+xml",
+			wantBody:        "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<problem xmlns=\"urn:ietf:rfc:7807\"><title>Bad Request</title><status>400</status><detail>invalid</detail></problem>",
+		},
+		{
+			name:            "text",
+			accept:          "text/plain",
+			problem:         &render.Problem{},
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "500 Internal Server Error",
+		},
+		{
+			name:            "not acceptable keeps the status",
+			accept:          "image/png",
+			problem:         render.NewProblem(http.StatusNotFound, ""),
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/problem+json",
+			wantBody:        "{\"status\":404,\"title\":\"Not Found\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			status, title := tt.problem.Status, tt.problem.Title
+			render.Default.Problem(w, r, tt.problem)
+			if tt.problem.Status != status || tt.problem.Title != title {
+				t.Errorf("Problem got: modified to %v %v want: %v %v", tt.problem.Status, tt.problem.Title, status, title)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("StatusCode got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type got: %v want: %v", got, tt.wantContentType)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Body got: %q want: %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestHandleErrors(t *testing.T) {
+	r := michi.NewRouter()
+	render.Default.HandleErrors(r)
+	r.Route("/a", func(r *michi.Router) {
+		r.HandleFunc("GET /b", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		wantStatus int
+		wantBody   string
+		wantAllow  string
+	}{
+		{
+			name:       "not found",
+			method:     http.MethodGet,
+			url:        "https://example.com/x",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "{\"status\":404,\"title\":\"Not Found\"}\n",
+		},
+		{
+			name:       "not found in sub router",
+			method:     http.MethodGet,
+			url:        "https://example.com/a/x",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "{\"status\":404,\"title\":\"Not Found\"}\n",
+		},
+		{
+			name:       "method not allowed in sub router",
+			method:     http.MethodPost,
+			url:        "https://example.com/a/b",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "{\"status\":405,\"title\":\"Method Not Allowed\"}\n",
+			wantAllow:  "GET, HEAD",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("StatusCode got: %v want: %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("Body got: %q want: %q", got, tt.wantBody)
+			}
+			if got := w.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow got: %v want: %v", got, tt.wantAllow)
+			}
+		})
+	}
+}
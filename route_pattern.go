@@ -0,0 +1,73 @@
+package michi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type routePatternKey struct{}
+
+// matchedRoute holds the pattern of the handler serving the request.
+// It is shared by the requests cloned by middlewares, so that the outer middlewares can read it after the handler returns.
+type matchedRoute struct {
+	// mu guards pattern, which may be written by a handler running in another goroutine
+	mu      sync.Mutex
+	pattern string
+	// router is the outermost Router serving the request
+	router *Router
+}
+
+func (m *matchedRoute) getPattern() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pattern
+}
+
+func (m *matchedRoute) setPattern(pattern string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pattern = pattern
+}
+
+// RoutePattern returns the full pattern of the route serving the request such as "GET /users/{id}",
+// or an empty string if no route matches.
+// Middlewares added by Use can read it after calling the next handler, even if the request is cloned.
+func RoutePattern(r *http.Request) string {
+	if m, ok := r.Context().Value(routePatternKey{}).(*matchedRoute); ok {
+		return m.getPattern()
+	}
+	return ""
+}
+
+// DetachRoute returns a context having its own copy of the matched route of ctx, and a function copying it back.
+// Middlewares running the handler in another goroutine such as Timeout use it,
+// so that the handler does not change the route read by the outer middlewares after they stop waiting for it.
+func DetachRoute(ctx context.Context) (context.Context, func()) {
+	m, ok := ctx.Value(routePatternKey{}).(*matchedRoute)
+	if !ok {
+		return ctx, func() {}
+	}
+	detached := &matchedRoute{pattern: m.getPattern(), router: m.router}
+	return context.WithValue(ctx, routePatternKey{}, detached), func() {
+		m.setPattern(detached.getPattern())
+	}
+}
+
+// withMatchedRoute returns the request having a matchedRoute of router in its context, it is r itself if r already has it
+func withMatchedRoute(r *http.Request, router *Router) *http.Request {
+	if _, ok := r.Context().Value(routePatternKey{}).(*matchedRoute); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), routePatternKey{}, &matchedRoute{router: router}))
+}
+
+// recordPattern records the pattern of the route before executing h
+func recordPattern(pattern string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m, ok := r.Context().Value(routePatternKey{}).(*matchedRoute); ok {
+			m.setPattern(pattern)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
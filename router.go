@@ -16,15 +16,46 @@ type Router struct {
 	subRouterMiddlewares []func(http.Handler) http.Handler
 	// serveMux is the http.ServeMux for Router
 	serveMux *http.ServeMux
+	// routes are the patterns registered on serveMux
+	// it is shared with the routers created by With and Group because they share serveMux
+	routes *[]route
 	// executedRouteOrHandle is true if the Route or Handle is executed
 	executedRouteOrHandle bool
 	// inGroupOrWith is true if the router is in Group or With
 	inGroupOrWith bool
+	// exactMatch is true if the patterns are registered as exact match like chi
+	exactMatch bool
+	// errorHandlers are shared with the sub routers created by Route, and the routers created by With and Group
+	errorHandlers *errorHandlers
+}
+
+// errorHandlers respond instead of http.ServeMux when no route matches
+type errorHandlers struct {
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+}
+
+// Option is an option for NewRouter
+type Option func(r *Router)
+
+// ExactMatch treats every pattern registered by Handle and HandleFunc as exact match like chi,
+// unless it ends in a {name...} wildcard. {$} is appended to the patterns ending with a slash,
+// so "/a/" matches only "/a/", not "/a/b". It is also applied to the sub routers created by Route.
+//
+// To mount a handler on the whole sub tree, use Route or a {name...} wildcard such as "/a/{path...}".
+func ExactMatch() Option {
+	return func(r *Router) {
+		r.exactMatch = true
+	}
 }
 
 // NewRouter creates a new Router
-func NewRouter() *Router {
-	return newRouter("")
+func NewRouter(options ...Option) *Router {
+	r := newRouter("")
+	for _, option := range options {
+		option(r)
+	}
+	return r
 }
 
 func newRouter(path string) *Router {
@@ -33,6 +64,8 @@ func newRouter(path string) *Router {
 		handlerMiddlewares:    nil,
 		subRouterMiddlewares:  nil,
 		serveMux:              http.NewServeMux(),
+		routes:                &[]route{},
+		errorHandlers:         &errorHandlers{},
 		executedRouteOrHandle: false,
 		inGroupOrWith:         false,
 	}
@@ -44,16 +77,57 @@ func (r *Router) cloneForWith() *Router {
 		subRouterMiddlewares: r.subRouterMiddlewares,
 		handlerMiddlewares:   r.handlerMiddlewares,
 		serveMux:             r.serveMux,
+		routes:               r.routes,
+		errorHandlers:        r.errorHandlers,
 		// After executing With and Group, set executedRouteOrHandle to false. Otherwise, it will panic with r.Group -> r.Use
 		executedRouteOrHandle: false,
 		inGroupOrWith:         true,
+		exactMatch:            r.exactMatch,
 	}
 }
 
 // ServeHTTP is the single method of the http.Handler interface that makes
 // Mux interoperable with the standard library.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	chain(r.subRouterMiddlewares, r.serveMux).ServeHTTP(w, req)
+	chain(r.subRouterMiddlewares, http.HandlerFunc(r.serveMuxHTTP)).ServeHTTP(w, withMatchedRoute(req, r))
+}
+
+// serveMuxHTTP serves the request with serveMux, or the errorHandlers if no route matches
+func (r *Router) serveMuxHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.errorHandlers.notFound == nil && r.errorHandlers.methodNotAllowed == nil {
+		r.serveMux.ServeHTTP(w, req)
+		return
+	}
+	h, pattern := r.serveMux.Handler(req)
+	if pattern != "" {
+		r.serveMux.ServeHTTP(w, req)
+		return
+	}
+	// http.ServeMux responds 404 or 405 by itself, h is not a registered handler, so it is safe to execute
+	dw := &discardResponseWriter{header: http.Header{}}
+	h.ServeHTTP(dw, req)
+	switch {
+	case dw.statusCode == http.StatusNotFound && r.errorHandlers.notFound != nil:
+		r.errorHandlers.notFound.ServeHTTP(w, req)
+	case dw.statusCode == http.StatusMethodNotAllowed && r.errorHandlers.methodNotAllowed != nil:
+		w.Header().Set("Allow", dw.header.Get("Allow"))
+		r.errorHandlers.methodNotAllowed.ServeHTTP(w, req)
+	default:
+		r.serveMux.ServeHTTP(w, req)
+	}
+}
+
+// NotFound sets the handler responding when no route matches instead of http.NotFound.
+// It is applied to the whole router tree including sub routers created by Route.
+func (r *Router) NotFound(h http.Handler) {
+	r.errorHandlers.notFound = h
+}
+
+// MethodNotAllowed sets the handler responding when routes match the path but not the method.
+// The Allow header is set before the handler is executed.
+// It is applied to the whole router tree including sub routers created by Route.
+func (r *Router) MethodNotAllowed(h http.Handler) {
+	r.errorHandlers.methodNotAllowed = h
 }
 
 // Use appends a middleware handler to the Mux middleware stack.
@@ -99,8 +173,11 @@ func (r *Router) Route(pattern string, fn func(sub *Router)) {
 	}
 
 	subRouter := newRouter(joinPathAndPattern(r.path, pattern))
+	subRouter.exactMatch = r.exactMatch
+	subRouter.errorHandlers = r.errorHandlers
 	fn(subRouter)
 	r.serveMux.Handle(subRouter.path, subRouter)
+	*r.routes = append(*r.routes, route{method: "", path: subRouter.path, handler: subRouter, sub: subRouter})
 
 	r.executedRouteOrHandle = true
 }
@@ -115,15 +192,60 @@ func (r *Router) HandleFunc(pattern string, handlerFunc http.HandlerFunc) {
 // execute the `handler` http.Handler.
 func (r *Router) Handle(pattern string, handler http.Handler) {
 	method, path := methodAndPath(pattern)
+	if r.exactMatch {
+		path = exactPath(path)
+	}
 	fullPath := joinPathAndPattern(r.path, path)
 	// The chain of handlerMiddlewares is done in Handle, not ServeHTTP.
 	// This is because it does not work correctly when Handle is executed after With.
 	// The reason it doesn't work correctly is that a different Router is created with With,
 	// and the handlerMiddlewares registered with With are not applied when ServeHTTP is executed.
-	r.serveMux.Handle(joinMethodAndPath(method, fullPath), chain(r.handlerMiddlewares, handler))
+	h := chain(r.handlerMiddlewares, handler)
+	r.serveMux.Handle(joinMethodAndPath(method, fullPath), recordPattern(joinMethodAndPath(method, fullPath), h))
+	*r.routes = append(*r.routes, route{method: method, path: fullPath, handler: h, sub: nil})
 	r.executedRouteOrHandle = true
 }
 
+// route is a pattern registered on the http.ServeMux of a Router
+type route struct {
+	// method is the method of the pattern, empty matches any method
+	method string
+	// path is the full path of the pattern, it may start with a host
+	path string
+	// handler is the handler registered on the http.ServeMux, including handlerMiddlewares
+	handler http.Handler
+	// sub is the sub router if the route is registered by Route
+	sub *Router
+}
+
+// pattern returns the pattern registered on the http.ServeMux
+func (rt route) pattern() string {
+	return joinMethodAndPath(rt.method, rt.path)
+}
+
+// Patterns returns the full patterns of the routes registered by Handle on r and its sub routers
+// in registration order, such as "GET /users/{id}". They are the values of RoutePattern.
+func (r *Router) Patterns() []string {
+	var patterns []string
+	for _, rt := range r.leafRoutes() {
+		patterns = append(patterns, rt.pattern())
+	}
+	return patterns
+}
+
+// leafRoutes returns the routes registered by Handle on r and its sub routers
+func (r *Router) leafRoutes() []route {
+	var routes []route
+	for _, rt := range *r.routes {
+		if rt.sub != nil {
+			routes = append(routes, rt.sub.leafRoutes()...)
+			continue
+		}
+		routes = append(routes, rt)
+	}
+	return routes
+}
+
 func chain(middlewares []func(http.Handler) http.Handler, handler http.Handler) http.Handler {
 	for i := range middlewares {
 		handler = middlewares[len(middlewares)-1-i](handler)
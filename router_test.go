@@ -5,6 +5,7 @@ import (
 	"github.com/go-michi/michi/middleware"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 
 	"github.com/go-michi/michi"
@@ -1096,6 +1097,199 @@ func TestServeMuxWithHostAndMethod(t *testing.T) {
 	}
 }
 
+func TestExactMatch(t *testing.T) {
+	h := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		})
+	}
+	r := michi.NewRouter(michi.ExactMatch())
+	r.Handle("/", h("root"))
+	r.Handle("/a/", h("a"))
+	r.Handle("/b/{path...}", h("b"))
+	r.With().Handle("/c/", h("c"))
+	r.Route("/d", func(r *michi.Router) {
+		r.Handle("/", h("d"))
+		r.Handle("/e/", h("e"))
+	})
+	tests := []struct {
+		requestURL string
+		result     string
+		statusCode int
+	}{
+		{requestURL: "https://example.com/", result: "root", statusCode: 200},
+		{requestURL: "https://example.com/x", result: "404 page not found\n", statusCode: 404},
+		{requestURL: "https://example.com/a/", result: "a", statusCode: 200},
+		{requestURL: "https://example.com/a/x", result: "404 page not found\n", statusCode: 404},
+		{requestURL: "https://example.com/b/x/y", result: "b", statusCode: 200},
+		{requestURL: "https://example.com/c/x", result: "404 page not found\n", statusCode: 404},
+		{requestURL: "https://example.com/d/", result: "d", statusCode: 200},
+		{requestURL: "https://example.com/d/x", result: "404 page not found\n", statusCode: 404},
+		{requestURL: "https://example.com/d/e/", result: "e", statusCode: 200},
+		{requestURL: "https://example.com/d/e/x", result: "404 page not found\n", statusCode: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.requestURL, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.requestURL, nil))
+			if got := w.Body.String(); got != tt.result {
+				t.Errorf("Result got: %v want: %v", got, tt.result)
+			}
+			if got := w.Result().StatusCode; got != tt.statusCode {
+				t.Errorf("Result got: %v want: %v", got, tt.statusCode)
+			}
+		})
+	}
+}
+
+func TestNotFoundAndMethodNotAllowed(t *testing.T) {
+	h := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		})
+	}
+	r := michi.NewRouter()
+	r.Route("/a", func(r *michi.Router) {
+		r.Handle("GET /b", h("b"))
+	})
+	r.NotFound(h("not found"))
+	r.MethodNotAllowed(h("method not allowed"))
+	tests := []struct {
+		method     string
+		requestURL string
+		result     string
+		allow      string
+	}{
+		{method: http.MethodGet, requestURL: "https://example.com/a/b", result: "b", allow: ""},
+		{method: http.MethodGet, requestURL: "https://example.com/x", result: "not found", allow: ""},
+		{method: http.MethodGet, requestURL: "https://example.com/a/x", result: "not found", allow: ""},
+		{method: http.MethodPost, requestURL: "https://example.com/a/b", result: "method not allowed", allow: "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.requestURL, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.requestURL, nil))
+			if got := w.Body.String(); got != tt.result {
+				t.Errorf("Result got: %v want: %v", got, tt.result)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow got: %v want: %v", got, tt.allow)
+			}
+		})
+	}
+}
+
+func TestRoutePattern(t *testing.T) {
+	var got string
+	r := michi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// the request is cloned by the inner middlewares
+			next.ServeHTTP(w, req.WithContext(req.Context()))
+			got = michi.RoutePattern(req)
+		})
+	})
+	r.Handle("GET /a/{id}", http.NotFoundHandler())
+	r.Route("/b", func(r *michi.Router) {
+		r.Use(middleware.StripSlashes)
+		r.With(middleware.StripSlashes).Handle("POST /{name...}", http.NotFoundHandler())
+	})
+	tests := []struct {
+		method     string
+		requestURL string
+		pattern    string
+	}{
+		{method: http.MethodGet, requestURL: "https://example.com/a/1", pattern: "GET /a/{id}"},
+		{method: http.MethodPost, requestURL: "https://example.com/b/c/d", pattern: "POST /b/{name...}"},
+		{method: http.MethodGet, requestURL: "https://example.com/x", pattern: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.requestURL, func(t *testing.T) {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.requestURL, nil))
+			if got != tt.pattern {
+				t.Errorf("RoutePattern got: %v want: %v", got, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestDetachRoute(t *testing.T) {
+	var before, detached, after string
+	r := michi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, restore := michi.DetachRoute(req.Context())
+			next.ServeHTTP(w, req.WithContext(ctx))
+			detached = michi.RoutePattern(req.WithContext(ctx))
+			before = michi.RoutePattern(req)
+			restore()
+			after = michi.RoutePattern(req)
+		})
+	})
+	r.Handle("GET /a/{id}", http.NotFoundHandler())
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://example.com/a/1", nil))
+	if detached != "GET /a/{id}" || before != "" || after != "GET /a/{id}" {
+		t.Errorf("RoutePattern got: detached %q before restore %q after restore %q want: GET /a/{id}, empty, GET /a/{id}", detached, before, after)
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	var got []string
+	r := michi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// available before the next handler
+			got = michi.RouteMethods(req)
+			next.ServeHTTP(w, req)
+		})
+	})
+	r.Handle("GET /items", http.NotFoundHandler())
+	r.Handle("POST /items", http.NotFoundHandler())
+	r.Handle("/any", http.NotFoundHandler())
+	r.Route("/api", func(r *michi.Router) {
+		r.Handle("PUT /items/{id}", http.NotFoundHandler())
+		r.Handle("PURGE /items/{id}", http.NotFoundHandler())
+		r.Handle("DELETE /items/{id}/", http.NotFoundHandler())
+	})
+	tests := []struct {
+		requestURL string
+		methods    []string
+	}{
+		{requestURL: "https://example.com/items", methods: []string{"GET", "HEAD", "POST"}},
+		{requestURL: "https://example.com/any", methods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "PURGE"}},
+		{requestURL: "https://example.com/api/items/1", methods: []string{"PUT", "PURGE"}},
+		{requestURL: "https://example.com/api/items/1/x", methods: []string{"DELETE"}},
+		{requestURL: "https://example.com/x", methods: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.requestURL, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.requestURL, nil)
+			if methods := r.AllowedMethods(req); !slices.Equal(methods, tt.methods) {
+				t.Errorf("AllowedMethods got: %v want: %v", methods, tt.methods)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			if !slices.Equal(got, tt.methods) {
+				t.Errorf("RouteMethods got: %v want: %v", got, tt.methods)
+			}
+		})
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	h := http.NotFoundHandler()
+	r := michi.NewRouter()
+	r.Handle("GET /a/{$}", h)
+	r.Route("/b", func(r *michi.Router) {
+		r.Handle("GET /{id}", h)
+		r.With().Handle("POST /{id}", h)
+	})
+	r.Handle("example.com/c", h)
+	want := []string{"GET /a/{$}", "GET /b/{id}", "POST /b/{id}", "example.com/c"}
+	if got := r.Patterns(); !slices.Equal(got, want) {
+		t.Errorf("Patterns got: %v want: %v", got, want)
+	}
+}
+
 func Example() {
 	h := func(name string) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {